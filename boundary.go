@@ -0,0 +1,138 @@
+package greact
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ErrorInfo describes where an error caught by an ErrorBoundary happened.
+type ErrorInfo struct {
+	// ComponentStack lists the component that failed followed by the
+	// components that rendered it.
+	ComponentStack ComponentStack
+}
+
+// ComponentStack is a list of component names starting with the component
+// that failed up to the root of the tree.
+type ComponentStack []string
+
+func (s ComponentStack) String() string {
+	var b strings.Builder
+	for _, name := range s {
+		b.WriteString("\n    in ")
+		b.WriteString(name)
+	}
+	return b.String()
+}
+
+func (s ComponentStack) name() string {
+	if len(s) == 0 {
+		return "unknown component"
+	}
+	return s[0]
+}
+
+// componentStack returns the stack of components from cmp to the root.
+func componentStack(cmp Component) ComponentStack {
+	var s ComponentStack
+	for c := cmp; c != nil; c = parentOf(c) {
+		s = append(s, Name(c))
+	}
+	return s
+}
+
+// parentOf returns the component that rendered cmp, this is the component
+// that returned cmp from its Render method or the one whose tree contains cmp.
+func parentOf(cmp Component) Component {
+	core := cmp.core()
+	if core.parentComponent != nil {
+		return core.parentComponent
+	}
+	return core.owner
+}
+
+// renderError is a panic raised while rendering a component.
+type renderError struct {
+	component Component
+	err       error
+	stack     ComponentStack
+}
+
+func newRenderError(cmp Component, v interface{}) *renderError {
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	e := &renderError{component: cmp, err: err}
+	if cmp != nil {
+		e.stack = componentStack(cmp)
+	}
+	return e
+}
+
+func (e *renderError) Error() string {
+	return fmt.Sprintf("greact: rendering %s: %v%s", e.stack.name(), e.err, e.stack)
+}
+
+// callRender calls the Render method of cmp, a panic is raised again as a
+// renderError recording the component stack.
+func callRender(cmp Component, ctx context.Context, props Props, state State) *Node {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*renderError); ok {
+				panic(r)
+			}
+			panic(newRenderError(cmp, r))
+		}
+	}()
+	return cmp.Render(ctx, props, state)
+}
+
+// renderBoundary renders b, a component implementing ErrorBoundary. When
+// rendering its children panics the error is passed to ComponentDidCatch and
+// b is rendered again so it can show a fallback.
+//
+// Errors raised by b itself, or by the fallback, are left to the next boundary
+// up the tree.
+func (v *Vected) renderBoundary(b ErrorBoundary, mode RenderMode, mountAll, isChild bool) {
+	cmp := b.(Component)
+	e := v.catch(func() {
+		v.render(cmp, mode, mountAll, isChild)
+	})
+	if e == nil {
+		return
+	}
+	if e.component == cmp {
+		panic(e)
+	}
+	b.ComponentDidCatch(e.err, ErrorInfo{ComponentStack: e.stack})
+	v.render(cmp, mode, mountAll, isChild)
+}
+
+// catch calls fn and returns the panic raised by it if any. Panics raised
+// outside Render are attributed to the component being rendered.
+//
+// The render state of v is restored so rendering can go on after a panic.
+func (v *Vected) catch(fn func()) (e *renderError) {
+	owner, index := v.owner, v.childIndex
+	svg, math := v.isSVGMode, v.isMathMLMode
+	mounts := v.mounts.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if e, ok = r.(*renderError); !ok {
+				e = newRenderError(v.owner, r)
+			}
+			v.owner, v.childIndex = owner, index
+			v.isSVGMode, v.isMathMLMode = svg, math
+
+			// components mounted by the failed render never made it to the dom.
+			for v.mounts.Len() > mounts {
+				v.mounts.Remove(v.mounts.Front())
+			}
+		}
+	}()
+	fn()
+	return nil
+}
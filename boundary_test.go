@@ -0,0 +1,175 @@
+package greact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type broken struct {
+	Core
+}
+
+func (b *broken) Render(ctx context.Context, props Props, state State) *Node {
+	if props["fail"] == true {
+		panic(errors.New("boom"))
+	}
+	return NewNode(ElementNode, "", "span", nil)
+}
+
+type boundary struct {
+	Core
+	err  error
+	info ErrorInfo
+}
+
+func (b *boundary) ComponentDidCatch(err error, info ErrorInfo) {
+	b.err = err
+	b.info = info
+	b.SetStateMode(No, State{"failed": true})
+}
+
+func (b *boundary) Render(ctx context.Context, props Props, state State) *Node {
+	if state["failed"] == true {
+		return NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", "fallback", nil))
+	}
+	fail := props["fail"] == true || state["fail"] == true
+	return NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "broken", Attrs(Attr("", "fail", fail))),
+	)
+}
+
+func TestErrorBoundary(t *testing.T) {
+	render := func(el *object) string {
+		var buf bytes.Buffer
+		if err := renderObject(&buf, el.children[0].children[0]); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	t.Run("mount", func(t *testing.T) {
+		v := New()
+		v.Document = newObject()
+		v.Register("boundary", &boundary{})
+		v.Register("broken", &broken{})
+		el := newObject()
+		v.Render(NewNode(ElementNode, "", "section", nil,
+			NewNode(ElementNode, "", "boundary", Attrs(Attr("", "fail", true))),
+		), el)
+		if got := render(el); got != "<p>fallback</p>" {
+			t.Errorf("expected the fallback got %s", got)
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		v := New()
+		v.Document = newObject()
+		v.Register("boundary", &boundary{})
+		v.Register("broken", &broken{})
+		el := newObject()
+		v.Render(NewNode(ElementNode, "", "section", nil,
+			NewNode(ElementNode, "", "boundary", nil),
+		), el)
+		if got := render(el); got != "<div><span></span></div>" {
+			t.Fatalf("unexpected first render %s", got)
+		}
+		b := v.findComponent(el.children[0].children[0]).(*boundary)
+		b.SetStateMode(Sync, State{"fail": true})
+		if got := render(el); got != "<p>fallback</p>" {
+			t.Errorf("expected the fallback got %s", got)
+		}
+		if b.err == nil || b.err.Error() != "boom" {
+			t.Errorf("expected boom got %v", b.err)
+		}
+		expect := "\n    in broken\n    in boundary"
+		if got := b.info.ComponentStack.String(); got != expect {
+			t.Errorf("expected %q got %q", expect, got)
+		}
+	})
+}
+
+func TestVected_OnError(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("broken", &broken{})
+	var (
+		err   error
+		stack ComponentStack
+	)
+	v.OnError(func(e error, info ComponentStack) {
+		err, stack = e, info
+	})
+	p := v.Render(NewNode(ElementNode, "", "broken", nil), newObject())
+	cmp := v.findComponent(p)
+	cmp.core().props = Props{"fail": true}
+	cmp.core().SetState(State{})
+	v.Flush()
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expected boom got %v", err)
+	}
+	if len(stack) != 1 || stack[0] != "broken" {
+		t.Errorf("expected [broken] got %v", stack)
+	}
+}
+
+type fragile struct {
+	Core
+}
+
+func (f *fragile) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", fmt.Sprint(state["count"]), nil)),
+		NewNode(ElementNode, "", "broken", Attrs(Attr("", "fail", state["fail"]))),
+	)
+}
+
+func TestVected_OnError_rollback(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("fragile", &fragile{})
+	v.Register("broken", &broken{})
+	var errs int
+	v.OnError(func(error, ComponentStack) {
+		errs++
+	})
+	div := v.Render(NewNode(ElementNode, "", "fragile", nil), newObject()).(*object)
+	cmp := v.findComponent(div)
+	cmp.core().SetStateMode(No, State{"count": 1})
+	cmp.core().ForceUpdate()
+	var good bytes.Buffer
+	renderObject(&good, div)
+
+	cmp.core().SetState(State{"count": 2, "fail": true})
+	v.Flush()
+	if errs != 1 {
+		t.Fatalf("expected one error got %d", errs)
+	}
+	var got bytes.Buffer
+	renderObject(&got, div)
+	if got.String() != good.String() {
+		t.Errorf("expected the last good dom %s got %s", good.String(), got.String())
+	}
+	if s := cmp.core().State(); s["count"] != 1 {
+		t.Errorf("expected the last good state got %v", s)
+	}
+
+	// without OnError the error is raised after rolling back.
+	v.OnError(nil)
+	cmp.core().SetStateMode(No, State{"count": 3, "fail": true})
+	cmp.core().dirty = true
+	v.queue.Push(cmp)
+	func() {
+		defer func() {
+			if r := recover(); r == nil || fmt.Sprint(r) != "boom" {
+				t.Errorf("expected boom to be raised got %v", r)
+			}
+		}()
+		v.Flush()
+	}()
+	got.Reset()
+	renderObject(&got, div)
+	if got.String() != good.String() {
+		t.Errorf("expected the last good dom without OnError %s got %s", good.String(), got.String())
+	}
+}
@@ -0,0 +1,40 @@
+package greact
+
+import "context"
+
+type serverKey struct{}
+
+// ServerContext returns a copy of ctx marking the render as happening on the
+// server, components can check it with IsServer. RenderToString renders with
+// a context derived from it.
+func ServerContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serverKey{}, true)
+}
+
+// IsServer returns true if ctx was derived from ServerContext.
+func IsServer(ctx context.Context) bool {
+	ok, _ := ctx.Value(serverKey{}).(bool)
+	return ok
+}
+
+// ClientOnly is a component for children that depend on browser only apis. On
+// the server it renders the node passed in the fallback prop, or nothing, and
+// on the client its children.
+//
+// 	<clientonly fallback={spinner}>
+// 		<map />
+// 	</clientonly>
+//
+// When hydrating server markup the fallback is replaced with the child.
+type ClientOnly struct {
+	Core
+}
+
+// Render implements Component.
+func (c *ClientOnly) Render(ctx context.Context, props Props, state State) *Node {
+	if IsServer(ctx) {
+		fallback, _ := props["fallback"].(*Node)
+		return fallback
+	}
+	return Frag(props.Children()...)
+}
@@ -0,0 +1,32 @@
+package greact
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestClientOnly(t *testing.T) {
+	h := NewNode
+	node := h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "clientonly", Attrs(
+			Attr("", "fallback", h(ElementNode, "", "span", nil, h(TextNode, "", "loading", nil))),
+		),
+			h(ElementNode, "", "canvas", nil),
+		),
+	)
+	render := func(ctx context.Context) string {
+		v := New()
+		v.Document = newObject()
+		v.Register("clientonly", &ClientOnly{})
+		var buf bytes.Buffer
+		renderObject(&buf, v.RenderContext(ctx, node, newObject()).(*object))
+		return buf.String()
+	}
+	if got := render(ServerContext(context.Background())); got != "<div><span>loading</span></div>" {
+		t.Errorf("expected the fallback on the server got %s", got)
+	}
+	if got := render(context.Background()); got != "<div><canvas></canvas></div>" {
+		t.Errorf("expected the children on the client got %s", got)
+	}
+}
@@ -18,8 +18,14 @@ import (
 
 func RenderCMD() cli.Command {
 	return cli.Command{
-		Name:   "render",
-		Usage:  "generates Render functions for components",
+		Name:  "render",
+		Usage: "generates Render functions for components",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "strict",
+				Usage: "reject templates with unclosed tags or invalid nesting",
+			},
+		},
 		Action: render,
 	}
 }
@@ -50,7 +56,7 @@ func renderDir(ctx *cli.Context) error {
 		return err
 	}
 	for pkg := range pkgs {
-		err = processPackage(path, pkgs[pkg])
+		err = processPackage(path, pkgs[pkg], ctx.Bool("strict"))
 		if err != nil {
 			return err
 		}
@@ -62,7 +68,7 @@ func renderFile(ctx *cli.Context) error {
 	return nil
 }
 
-func processPackage(path string, pkg *ast.Package) error {
+func processPackage(path string, pkg *ast.Package, strict bool) error {
 	ctxs := make(map[string]greact.GeneratorContext)
 
 	// First we collect all structs that implements that emebds greact.Core. Then
@@ -120,9 +126,13 @@ func processPackage(path string, pkg *ast.Package) error {
 											if ret, ok := rs.Results[0].(*ast.BasicLit); ok {
 												v := strings.TrimPrefix(ret.Value, "`")
 												v = strings.TrimSuffix(v, "`")
-												n, err := greact.ParseString(v)
+												parse := greact.Parse
+												if strict {
+													parse = greact.ParseStrict
+												}
+												n, err := parse(strings.NewReader(v))
 												if err != nil {
-													return err
+													return fmt.Errorf("%s: %v", ctx.StructName, err)
 												}
 												ctx.Node = n
 												ctxs[ctx.StructName] = ctx
@@ -0,0 +1,90 @@
+package greact
+
+import (
+	"context"
+	"fmt"
+)
+
+// Collapse is a component that shows its children while the open prop is true
+// and hides them otherwise, animating the height of its element in between.
+//
+// 	<collapse open={expanded} duration="200ms">
+// 		<details />
+// 	</collapse>
+//
+// duration is optional and defaults to 250ms.
+//
+// CSS can't transition to or from height: auto, so the height is measured from
+// the element's scrollHeight and swapped for a fixed one while animating. On
+// expand it goes from 0 to the measured height and becomes auto once the
+// transition ends, on collapse it is first fixed to the measured height and
+// set to 0 on the next frame.
+type Collapse struct {
+	Core
+	elem Element
+}
+
+// ComponentWillReceiveProps implements WillReceiveProps, it starts the
+// transition when open changes.
+func (c *Collapse) ComponentWillReceiveProps(ctx context.Context, props Props) {
+	open, _ := props["open"].(bool)
+	wasOpen, _ := c.props["open"].(bool)
+	if open == wasOpen || c.elem == nil {
+		return
+	}
+	height := fmt.Sprintf("%vpx", c.elem.Get("scrollHeight").Float())
+	c.SetState(State{"height": height, "closing": !open})
+}
+
+// ComponentDidUpdate implements DidUpdate, the fixed height set when closing
+// has been rendered so the next frame can transition it to 0.
+func (c *Collapse) ComponentDidUpdate(prevProps Props, prevState State) {
+	if closing, _ := c.state["closing"].(bool); closing {
+		c.SetState(State{"height": "0px", "closing": false})
+	}
+}
+
+func (c *Collapse) ref(elem interface{}) {
+	c.elem, _ = elem.(Element)
+}
+
+// transitionEnd lets an expanded element follow the size of its content.
+func (c *Collapse) transitionEnd(args []Value) {
+	if len(args) > 0 && c.elem != nil {
+		if target := args[0].Get("target"); Valid(target) && !IsEqual(target, c.elem) {
+			// a transition of a child bubbled up.
+			return
+		}
+	}
+	if open, _ := c.props["open"].(bool); open {
+		c.SetState(State{"height": "auto"})
+	}
+}
+
+// Render implements Component.
+func (c *Collapse) Render(ctx context.Context, props Props, state State) *Node {
+	open, _ := props["open"].(bool)
+	height, _ := state["height"].(string)
+	if height == "" {
+		height = "0px"
+		if open {
+			height = "auto"
+		}
+	}
+	duration := props.String("duration")
+	if duration == "" {
+		duration = "250ms"
+	}
+	return NewNode(ElementNode, "", "div",
+		Attrs(
+			Attr("", "ref", c.ref),
+			Attr("", "style", map[string]string{
+				"height":     height,
+				"overflow":   "hidden",
+				"transition": "height " + duration,
+			}),
+			Attr("", "onTransitionEnd", c.transitionEnd),
+		),
+		props.Children()...,
+	)
+}
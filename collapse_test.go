@@ -0,0 +1,60 @@
+package greact
+
+import "testing"
+
+func TestCollapse(t *testing.T) {
+	var frames []func()
+	v := New(WithBackend(Backend{
+		Document:  newObject(),
+		Callbacks: callbacks,
+		Schedule: func(fn func()) {
+			frames = append(frames, fn)
+		},
+	}))
+	v.Register("collapse", &Collapse{})
+	h := NewNode
+	node := func(open bool) *Node {
+		return h(ElementNode, "", "div", nil,
+			h(ElementNode, "", "collapse", Attrs(Attr("", "open", open)),
+				h(ElementNode, "", "p", nil, h(TextNode, "", "details", nil)),
+			),
+		)
+	}
+	drain := func() {
+		for len(frames) > 0 {
+			fn := frames[0]
+			frames = frames[1:]
+			fn()
+		}
+	}
+	el := newObject()
+	div := v.Render(node(false), el).(*object)
+	elem := div.children[0]
+	height := func() string {
+		return elem.Get("style").Get("height").String()
+	}
+	if got := height(); got != "0px" {
+		t.Fatalf("expected a closed collapse to have no height got %q", got)
+	}
+	elem.Set("scrollHeight", 120)
+
+	v.Render(node(true), el, div)
+	if got := height(); got != "120px" {
+		t.Errorf("expected expanding to transition to the measured height got %q", got)
+	}
+	drain()
+	elem.dispatch("transitionend", newObject())
+	drain()
+	if got := height(); got != "auto" {
+		t.Errorf("expected an expanded collapse to follow its content got %q", got)
+	}
+
+	v.Render(node(false), el, div)
+	if got := height(); got != "120px" {
+		t.Errorf("expected collapsing to fix the measured height first got %q", got)
+	}
+	drain()
+	if got := height(); got != "0px" {
+		t.Errorf("expected collapsing to transition to 0 on the next frame got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package greact
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 )
@@ -18,6 +19,24 @@ const (
 // instead we will pass  the id which will be used to reference the
 // component.
 func (v *Vected) createComponent(ctx context.Context, cmp Component, props Props) Component {
+	return v.initComponent(ctx, newInstance(cmp, props), props)
+}
+
+// initComponent assigns the new instance ncmp an id and caches it.
+func (v *Vected) initComponent(ctx context.Context, ncmp Component, props Props) Component {
+	core := ncmp.core()
+	core.context = ctx
+	core.props = props
+	core.owner = v.owner
+	core.index = v.childIndex
+	core.id = idPool.Get().(int)
+	core.enqueue = v.queue
+	v.cache[core.id] = ncmp
+	return ncmp
+}
+
+// newInstance returns a new instance of the same type as cmp.
+func newInstance(cmp Component, props Props) Component {
 	var ncmp Component
 	if in, ok := cmp.(Constructor); ok {
 		ncmp = in.New(props)
@@ -36,17 +55,46 @@ func (v *Vected) createComponent(ctx context.Context, cmp Component, props Props
 		ncmp.core().constructor = constructor
 	}
 	core := ncmp.core()
-	core.context = ctx
-	core.props = props
-	core.id = idPool.Get().(int)
-	core.enqueue = v.queue
-	v.cache[core.id] = ncmp
+	if core.constructor == "" {
+		core.constructor = cmp.core().constructor
+	}
 	return ncmp
 }
 
+// Shallow renders a new instance of cmp with props and returns the tree
+// returned by its Render method, without touching the dom. Child components
+// in the tree are not rendered.
+//
+// Props from InitProps are used as defaults, the state starts from InitState
+// and is passed through DeriveState. A panic in Render is returned as an
+// error.
+func Shallow(cmp Component, props Props) (node *Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("greact: rendering %s: %v", Name(cmp), r)
+		}
+	}()
+	inst := newInstance(cmp, props)
+	if p, ok := inst.(InitProps); ok {
+		props = MergeProps(p.InitProps(), props)
+	}
+	var state State
+	if s, ok := inst.(InitState); ok {
+		state = s.InitState()
+	}
+	if d, ok := inst.(DerivedState); ok {
+		state = MergeState(state, d.DeriveState(props, state))
+	}
+	core := inst.core()
+	core.context = context.Background()
+	core.props = props
+	core.state = state
+	return inst.Render(core.context, props, state), nil
+}
+
 func (v *Vected) createComponentByName(ctx context.Context, name string, props Props) Component {
-	if c, ok := v.components[name]; ok {
-		return v.createComponent(ctx, c, props)
+	if r, ok := v.components[name]; ok {
+		return v.initComponent(ctx, r.instance(props), props)
 	}
 	return nil
 }
@@ -80,6 +128,12 @@ func (v *Vected) setProps(ctx context.Context, cmp Component, props Props, mode
 			m.ComponentWillReceiveProps(ctx, props)
 		}
 	}
+	if ctx != nil && ctx != core.context {
+		if core.prevContext == nil {
+			core.prevContext = core.context
+		}
+		core.context = ctx
+	}
 	if core.prevProps == nil {
 		core.prevProps = core.props
 	}
@@ -89,7 +143,7 @@ func (v *Vected) setProps(ctx context.Context, cmp Component, props Props, mode
 		if mode == Sync {
 			v.renderComponent(cmp, Sync, mountAll, false)
 		} else {
-			v.enqueueRender(cmp)
+			v.queue.enqueue(cmp)
 		}
 	}
 	if core.ref != nil {
@@ -106,15 +160,20 @@ func (v *Vected) isHigherOrder(node *Node) bool {
 	return false
 }
 
-func (v *Vected) getComponent(node *Node) Component {
-	return v.components[node.Data]
+func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool, isChild bool) {
+	if b, ok := cmp.(ErrorBoundary); ok {
+		v.renderBoundary(b, mode, mountAll, isChild)
+		return
+	}
+	v.render(cmp, mode, mountAll, isChild)
 }
 
-func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool, isChild bool) {
+func (v *Vected) render(cmp Component, mode RenderMode, mountAll bool, isChild bool) {
 	core := cmp.core()
 	if core.disable {
 		return
 	}
+	core.applyUpdaters()
 	props := core.props
 	xstate := core.state
 	context := core.context
@@ -162,6 +221,11 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 		core.state = xstate
 		core.context = context
 	}
+	if isUpdate != nil {
+		core.previousProps = prevProps
+		core.previousState = prevState
+		core.previousContext = prevContext
+	}
 	core.prevProps = nil
 	core.prevState = nil
 	core.prevContext = nil
@@ -169,26 +233,40 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 	core.dirty = false
 
 	if !skip {
-		rendered := cmp.Render(context, props, xstate)
+		var rendered *Node
+		if core.memoize && core.memo != nil &&
+			reflect.DeepEqual(core.memoProps, props) &&
+			reflect.DeepEqual(core.memoState, xstate) {
+			rendered = core.memo
+		} else {
+			rendered = callRender(cmp, context, props, xstate)
+			if core.memoize {
+				core.memo = rendered
+				core.memoProps = props
+				core.memoState = xstate
+			}
+		}
+		if rendered == nil {
+			// nothing to render
+			rendered = Empty()
+		}
+		rendered = fragmentRoot(rendered)
 		if ctx, ok := cmp.(WithContext); ok {
 			context = ctx.WithContext(context)
 		}
-		childComponent := v.getComponent(rendered)
 		var toUnmount Component
 		var base Element
+		prevOwner, prevIndex := v.owner, v.childIndex
+		v.owner, v.childIndex = cmp, -1
 		if v.isHigherOrder(rendered) {
 			childProps := getNodeProps(rendered)
 			inst = initialChildComponent
 
 			var validForProps = func() bool {
-				if inst != nil && sameConstructor(inst, childComponent) {
-					key := childProps.String("key")
-					ikey := inst.core().key
-					if key != "" && ikey != "" && key == ikey {
-						return true
-					}
-				}
-				return false
+				// like preact, an unkeyed child is reused as long as it is of the
+				// same type.
+				return inst != nil && inst.core().constructor == rendered.Data &&
+					childProps.String("key") == inst.core().key
 			}
 			if validForProps() {
 				v.setProps(context, inst, childProps, Sync, false)
@@ -196,10 +274,9 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 				// We must create a new initialChildComponent and set the current cmp as
 				// parent.
 				toUnmount = inst
-				inst = v.createComponent(context, childComponent, childProps)
+				inst = v.createComponentByName(context, rendered.Data, childProps)
 				core.component = inst
 				instanceCore := inst.core()
-				instanceCore.component = inst
 				if instanceCore.nextBase == nil {
 					instanceCore.nextBase = nextBase
 				}
@@ -226,11 +303,13 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 				base = v.diff(context, cbase, rendered, parent, mountAll || !Valid(isUpdate), true)
 			}
 		}
+		v.owner, v.childIndex = prevOwner, prevIndex
 		if Valid(initialBase) &&
-			!IsEqual(base, initialBase) {
-			// TODO: add inst!==initialChildComponent to the if condition
-			// Go doesnt support that operation on structs so I will need to use
-			// reflection for that or comeup with something else.
+			!IsEqual(base, initialBase) && inst != initialChildComponent {
+			// The root changed, either the element type returned by Render or the
+			// child component. Put the new base in place of the old one and
+			// recollect the old tree unless it belongs to a child component that is
+			// unmounted below.
 			baseParent := initialBase.Get("parentNode")
 			if Valid(baseParent) && !IsEqual(base, baseParent) {
 				baseParent.Call("replaceChild", base, initialBase)
@@ -243,6 +322,12 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 		if toUnmount != nil {
 			v.unmountComponent(toUnmount)
 		}
+		if v.PoolNodes {
+			if core.rendered != nil && core.rendered != rendered {
+				v.released = v.ownedNodes(v.released, core.rendered, propNodes(prevProps, props))
+			}
+			core.rendered = rendered
+		}
 		core.base = base
 		if Valid(base) && !isChild {
 			componentRef := cmp
@@ -259,7 +344,7 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 		}
 	}
 	if !Valid(isUpdate) || mountAll {
-		//TODO mounts.unshift(component);
+		v.mounts.PushFront(cmp)
 	} else if !skip {
 		// Ensure that pending componentDidMount() hooks of child components
 		// are called before the componentDidUpdate() hook in the parent.
@@ -269,8 +354,15 @@ func (v *Vected) renderComponent(cmp Component, mode RenderMode, mountAll bool,
 			u.ComponentDidUpdate(prevProps, prevState)
 		}
 	}
+	// callbacks passed to SetState run in order once the dom is up to date.
+	callbacks := core.renderCallbacks
+	core.renderCallbacks = nil
+	for _, fn := range callbacks {
+		fn()
+	}
 	if v.diffLevel == 0 && !isChild {
 		v.flushMounts()
+		v.releaseNodes()
 	}
 }
 
@@ -283,26 +375,6 @@ func getNodeProps(node *Node) Props {
 	return props
 }
 
-// sameConstructor returns true if both a and b were created form ain instance
-// of the same type.
-//
-// There is only one way a user can satisfy the Component interface which is by
-// embedding Core struct. So, we know bya a fact that it must be a pointer to
-// the struct.
-func sameConstructor(a, b Component) bool {
-	v1 := reflect.ValueOf(a)
-	v2 := reflect.ValueOf(b)
-	switch v1.Kind() {
-	case reflect.Ptr:
-		if v2.Kind() == reflect.Ptr {
-			v1 = v1.Elem()
-			v2 = v2.Elem()
-			return v1.Type() == v2.Type()
-		}
-	}
-	return false
-}
-
 // findComponent returns the component that rendered the node element. This
 // returns nil if the node wasn't a component.
 //
@@ -334,6 +406,7 @@ func (v *Vected) removeComponentRef(e Element) {
 			v.refs[i]--
 		}
 		e.Set(componentKey, 0)
+		e.Set(componentConstructor, "")
 	}
 }
 
@@ -356,10 +429,18 @@ func (v *Vected) unmountComponent(cmp Component) {
 	if core.component != nil {
 		v.unmountComponent(core.component)
 	} else if base != nil {
+		v.releaseRef(base)
+		v.releaseThrottled(base)
+		v.releaseListeners(base)
+		v.releasePortal(base)
+		v.forgetAttrs(base)
 		core.nextBase = base
 		RemoveNode(base)
 		v.removeChildren(base)
 	}
+	if core.ref != nil {
+		core.ref(nil)
+	}
 }
 
 func (v *Vected) removeChildren(node Element) {
@@ -379,3 +460,28 @@ type componentCache struct {
 	cache map[int64]Component
 	refs  map[int64]int64
 }
+
+// StringComponent returns a component whose Render returns the string from
+// fn, the string is rendered as a text node.
+//
+// 	v.Register("greeting", greact.StringComponent(
+// 		func(ctx context.Context, props greact.Props, state greact.State) string {
+// 			return "hello " + props.String("name")
+// 		},
+// 	))
+func StringComponent(fn func(context.Context, Props, State) string) Component {
+	return &stringComponent{fn: fn}
+}
+
+type stringComponent struct {
+	Core
+	fn func(context.Context, Props, State) string
+}
+
+func (s *stringComponent) New(Props) Component {
+	return &stringComponent{fn: s.fn}
+}
+
+func (s *stringComponent) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(TextNode, "", s.fn(ctx, props, state), nil)
+}
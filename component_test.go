@@ -0,0 +1,162 @@
+package greact
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// mountLogger renders its label, wrapped around a child mountLogger when the
+// child prop is set, and records ComponentDidMount calls in log.
+type mountLogger struct {
+	Core
+	rendered chan string
+}
+
+func (m *mountLogger) ComponentDidMount() {
+	log := m.props["log"].(*[]string)
+	*log = append(*log, m.props["label"].(string))
+}
+
+func (m *mountLogger) Render(ctx context.Context, props Props, state State) *Node {
+	label := props["label"].(string)
+	if m.rendered != nil {
+		m.rendered <- label
+	}
+	if child, ok := props["child"].(string); ok {
+		return NewNode(ElementNode, "", "logger", Attrs(
+			Attr("", "label", child),
+			Attr("", "log", props["log"]),
+		))
+	}
+	return NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", label, nil))
+}
+
+func TestVected_mountOrder(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("logger", &mountLogger{})
+	var log []string
+	v.Render(NewNode(ElementNode, "", "logger", Attrs(
+		Attr("", "label", "parent"),
+		Attr("", "child", "child"),
+		Attr("", "log", &log),
+	)), newObject())
+	if len(log) != 2 || log[0] != "child" || log[1] != "parent" {
+		t.Errorf("expected children to mount before their parent got %v", log)
+	}
+}
+
+func TestVected_syncProps(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("mountlogger", &mountLogger{})
+	var log []string
+	node := func(label string) *Node {
+		return NewNode(ElementNode, "", "mountlogger", Attrs(
+			Attr("", "label", label),
+			Attr("", "log", &log),
+		))
+	}
+	p := v.Render(node("a"), newObject()).(*object)
+	cmp := v.findComponent(p)
+	p = v.buildComponentFromVNode(context.Background(), p, node("b"), false, false).(*object)
+	if v.findComponent(p) != cmp {
+		t.Fatal("expected the component to be reused")
+	}
+	var got interface{}
+	for _, j := range p.children[0].journal {
+		if j[0] == "set" && j[1] == "nodeValue" {
+			got = j[2]
+		}
+	}
+	if got != "b" {
+		t.Errorf("expected new props to be rendered right away got %v", got)
+	}
+}
+
+func TestVected_setPropsAsync(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("logger", &mountLogger{})
+	var log []string
+	p := v.Render(NewNode(ElementNode, "", "logger", Attrs(
+		Attr("", "label", "a"),
+		Attr("", "log", &log),
+	)), newObject())
+	cmp := v.findComponent(p).(*mountLogger)
+	rendered := make(chan string, 1)
+	cmp.rendered = rendered
+	// left dirty by a render dropped from a full queue.
+	cmp.dirty = true
+	v.setProps(context.Background(), cmp, Props{"label": "b", "log": &log}, Async, false)
+	select {
+	case got := <-rendered:
+		if got != "b" {
+			t.Errorf("expected the queued render to use the new props got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the component to be queued and rendered")
+	}
+}
+
+// lifecycle records mounts and unmounts in the log prop under its registered
+// name.
+type lifecycle struct {
+	Core
+}
+
+func (l *lifecycle) record(event string) {
+	log := l.props["log"].(*[]string)
+	*log = append(*log, event+" "+Name(l))
+}
+
+func (l *lifecycle) ComponentDidMount() {
+	l.record("mount")
+}
+
+func (l *lifecycle) ComponentWillUnmount() {
+	l.record("unmount")
+}
+
+func (l *lifecycle) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", Name(l), nil))
+}
+
+func TestVected_keyedTypeChange(t *testing.T) {
+	for _, keyed := range []bool{true, false} {
+		v := New()
+		v.Document = newObject()
+		v.Register("foo", &lifecycle{})
+		v.Register("bar", &lifecycle{})
+		var log []string
+		h := NewNode
+		node := func(name string) *Node {
+			last := h(ElementNode, "", "span", nil)
+			if keyed {
+				last = h(ElementNode, "", "span", Attrs(Attr("", "key", "b")))
+			}
+			return h(ElementNode, "", "div", nil,
+				h(ElementNode, "", name, Attrs(Attr("", "key", "a"), Attr("", "log", &log))),
+				last,
+			)
+		}
+		el := newObject()
+		div := v.Render(node("foo"), el).(*object)
+		log = nil
+		v.Render(node("bar"), el, div)
+		if got := fmt.Sprint(log); got != "[unmount foo mount bar]" {
+			t.Errorf("keyed=%v: expected foo to unmount and bar to mount got %s", keyed, got)
+		}
+		if n := len(div.children); n != 2 {
+			t.Fatalf("keyed=%v: expected 2 children got %d", keyed, n)
+		}
+		if got := Name(v.findComponent(div.children[0])); got != "bar" {
+			t.Errorf("keyed=%v: expected bar at the key's position got %s", keyed, got)
+		}
+		if got := div.children[0].children[0].Get("nodeValue").String(); got != "bar" {
+			t.Errorf("keyed=%v: expected bar to be rendered got %q", keyed, got)
+		}
+	}
+}
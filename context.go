@@ -0,0 +1,103 @@
+package greact
+
+import "context"
+
+// Context is a value passed down the component tree without threading it
+// through props, see CreateContext.
+type Context struct {
+	// Default is the value seen by consumers without a Provider above them.
+	Default interface{}
+
+	// Provider is a component setting the value prop as the value seen by
+	// the components it renders.
+	Provider Component
+
+	// Consumer is a component rendering the node returned by its render prop,
+	// a func(interface{}) *Node called with the value.
+	Consumer Component
+}
+
+type contextKey struct {
+	c *Context
+}
+
+// CreateContext returns a new Context whose value is defaultValue until a
+// Provider sets it.
+//
+// 	theme := greact.CreateContext("light")
+// 	v.Register("themeprovider", theme.Provider)
+// 	v.Register("themeconsumer", theme.Consumer)
+//
+// 	<themeprovider value="dark">
+// 		<themeconsumer render={button} />
+// 	</themeprovider>
+//
+// The Provider stores the value in the context.Context passed to its
+// children with WithContext. When it is rendered again with a different value
+// the new context reaches the components below it as they are updated.
+// ComponentWillUpdate receives the new context while Context still returns
+// the previous one, so a component can compare the two. A component whose
+// ShouldComponentUpdate returns false stops the update and the components
+// below it keep seeing the old value until they are rendered again.
+func CreateContext(defaultValue interface{}) *Context {
+	c := &Context{Default: defaultValue}
+	c.Provider = &contextProvider{c: c}
+	c.Consumer = &contextConsumer{c: c}
+	return c
+}
+
+// Value returns the value of c in ctx, the value of the closest Provider or
+// Default.
+func (c *Context) Value(ctx context.Context) interface{} {
+	if ctx != nil {
+		if v, ok := ctx.Value(contextKey{c}).(providedValue); ok {
+			return v.v
+		}
+	}
+	return c.Default
+}
+
+// providedValue wraps values stored by providers so that a nil value is told
+// apart from a missing one.
+type providedValue struct {
+	v interface{}
+}
+
+type contextProvider struct {
+	Core
+	c *Context
+}
+
+// New implements Constructor, instances share the Context of the registered
+// Provider.
+func (p *contextProvider) New(Props) Component {
+	return &contextProvider{c: p.c}
+}
+
+// WithContext implements WithContext.
+func (p *contextProvider) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{p.c}, providedValue{p.props["value"]})
+}
+
+// Render implements Component.
+func (p *contextProvider) Render(ctx context.Context, props Props, state State) *Node {
+	return Frag(props.Children()...)
+}
+
+type contextConsumer struct {
+	Core
+	c *Context
+}
+
+// New implements Constructor.
+func (c *contextConsumer) New(Props) Component {
+	return &contextConsumer{c: c.c}
+}
+
+// Render implements Component.
+func (c *contextConsumer) Render(ctx context.Context, props Props, state State) *Node {
+	if fn, ok := props["render"].(func(interface{}) *Node); ok {
+		return fn(c.c.Value(ctx))
+	}
+	return nil
+}
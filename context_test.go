@@ -0,0 +1,66 @@
+package greact
+
+import (
+	"context"
+	"testing"
+)
+
+// contextSpy records the theme seen by ComponentWillUpdate.
+type contextSpy struct {
+	Core
+	theme *Context
+	seen  *[]string
+}
+
+func (s *contextSpy) New(Props) Component {
+	return &contextSpy{theme: s.theme, seen: s.seen}
+}
+
+func (s *contextSpy) ComponentWillUpdate(ctx context.Context, props Props, state State) Props {
+	*s.seen = append(*s.seen, s.theme.Value(s.Context()).(string)+"->"+s.theme.Value(ctx).(string))
+	return nil
+}
+
+func (s *contextSpy) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "i", nil)
+}
+
+func TestCreateContext(t *testing.T) {
+	theme := CreateContext("light")
+	var seen []string
+	v := New()
+	v.Document = newObject()
+	v.Register("themeprovider", theme.Provider)
+	v.Register("themeconsumer", theme.Consumer)
+	v.Register("spy", &contextSpy{theme: theme, seen: &seen})
+	h := NewNode
+	text := func(value interface{}) *Node {
+		return h(TextNode, "", value.(string), nil)
+	}
+	consumer := func() *Node {
+		return h(ElementNode, "", "themeconsumer", Attrs(Attr("", "render", text)))
+	}
+	node := func(value string) *Node {
+		return h(ElementNode, "", "div", nil,
+			h(ElementNode, "", "themeprovider", Attrs(Attr("", "value", value)),
+				h(ElementNode, "", "p", nil, consumer(), h(ElementNode, "", "spy", nil)),
+			),
+			h(ElementNode, "", "p", nil, consumer()),
+		)
+	}
+	el := newObject()
+	div := v.Render(node("dark"), el).(*object)
+	if got := div.children[0].children[0].nodeValue; got != "dark" {
+		t.Errorf("expected the provided value got %q", got)
+	}
+	if got := div.children[1].children[0].nodeValue; got != "light" {
+		t.Errorf("expected the default value outside the provider got %q", got)
+	}
+	v.Render(node("blue"), el, div)
+	if got := div.children[0].children[0].nodeValue; got != "blue" {
+		t.Errorf("expected consumers to see the new value got %q", got)
+	}
+	if len(seen) != 1 || seen[0] != "dark->blue" {
+		t.Errorf("expected ComponentWillUpdate to see the value change got %v", seen)
+	}
+}
@@ -0,0 +1,194 @@
+package greact
+
+import "context"
+
+// DragEvent describes a drag and drop gesture, it is passed to the callbacks
+// of Draggable and Droppable.
+type DragEvent struct {
+	// Data is the payload of the dragged Draggable, the value of its data prop.
+	Data interface{}
+
+	// X and Y are the position of the pointer relative to the viewport.
+	X, Y float64
+
+	// DX and DY are the distance moved since the drag started.
+	DX, DY float64
+}
+
+// dragSession is the drag in progress, there is at most one per Vected
+// instance.
+type dragSession struct {
+	source *Draggable
+	data   interface{}
+
+	startX, startY float64
+	x, y           float64
+
+	// listeners added to the document for the duration of the drag.
+	listeners []func()
+}
+
+func (s *dragSession) event() DragEvent {
+	return DragEvent{
+		Data: s.data,
+		X:    s.x,
+		Y:    s.y,
+		DX:   s.x - s.startX,
+		DY:   s.y - s.startY,
+	}
+}
+
+func (s *dragSession) move(ev Value) {
+	s.x, s.y = pointerPosition(ev)
+}
+
+// pointerPosition returns clientX and clientY of the pointer event ev.
+func pointerPosition(ev Value) (x, y float64) {
+	if !Valid(ev) {
+		return
+	}
+	if v := ev.Get("clientX"); v.Type() == TypeNumber {
+		x = v.Float()
+	}
+	if v := ev.Get("clientY"); v.Type() == TypeNumber {
+		y = v.Float()
+	}
+	return
+}
+
+// Draggable is a component whose children can be dragged with a mouse, pen or
+// touch pointer and dropped on a Droppable.
+//
+// 	<draggable data={card} onDragStart={lift}>
+// 		<card />
+// 	</draggable>
+//
+// The data prop is the payload handed to the Droppable, onDragStart is
+// optional and is called with a DragEvent when the pointer is pressed.
+type Draggable struct {
+	Core
+}
+
+// Render implements Component.
+func (d *Draggable) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "div",
+		Attrs(
+			Attr("", "style", "touch-action: none"),
+			Attr("", "onPointerDown", d.start),
+		),
+		props.Children()...,
+	)
+}
+
+// start begins a drag session. The pointermove and pointerup listeners are
+// added to the document, so the pointer is tracked outside of the element and
+// the pointerup reaches the Droppable under the pointer first.
+func (d *Draggable) start(args []Value) {
+	if d.enqueue == nil {
+		return
+	}
+	v := d.enqueue.v
+	if v.drag != nil {
+		v.endDrag()
+	}
+	s := &dragSession{source: d, data: d.props["data"]}
+	if len(args) > 0 {
+		s.move(args[0])
+		releaseCapture(args[0])
+	}
+	s.startX, s.startY = s.x, s.y
+	v.drag = s
+	listen := func(name string, fn func([]Value)) {
+		cb := v.cb(v.batched(fn))
+		v.Document.Call("addEventListener", name, cb, false)
+		s.listeners = append(s.listeners, func() {
+			v.Document.Call("removeEventListener", name, cb, false)
+			cb.Release()
+		})
+	}
+	listen("pointermove", func(args []Value) {
+		if len(args) > 0 {
+			s.move(args[0])
+		}
+	})
+	listen("pointerup", func([]Value) {
+		v.endDrag()
+	})
+	listen("pointercancel", func([]Value) {
+		v.endDrag()
+	})
+	if fn, ok := d.props["onDragStart"].(func(DragEvent)); ok {
+		fn(s.event())
+	}
+}
+
+// releaseCapture releases the pointer of the pointerdown event ev from its
+// target. Touch pointers are captured by the element they are pressed on, which
+// would then receive the pointerup instead of the Droppable under the pointer.
+func releaseCapture(ev Value) {
+	target := ev.Get("target")
+	id := ev.Get("pointerId")
+	if Valid(target) && id.Type() == TypeNumber &&
+		target.Get("releasePointerCapture").Type() == TypeFunction {
+		target.Call("releasePointerCapture", id)
+	}
+}
+
+// ComponentWillUnmount implements WillUnmount, a drag started by d is
+// cancelled.
+func (d *Draggable) ComponentWillUnmount() {
+	if d.enqueue == nil {
+		return
+	}
+	if v := d.enqueue.v; v.drag != nil && v.drag.source == d {
+		v.endDrag()
+	}
+}
+
+// endDrag removes the document listeners of the drag in progress.
+func (v *Vected) endDrag() {
+	s := v.drag
+	if s == nil {
+		return
+	}
+	v.drag = nil
+	for _, release := range s.listeners {
+		release()
+	}
+}
+
+// Droppable is a component accepting Draggable payloads dropped on its
+// children.
+//
+// 	<droppable onDrop={move}>
+// 		<column />
+// 	</droppable>
+//
+// onDrop is called with a DragEvent when a drag ends over the element.
+type Droppable struct {
+	Core
+}
+
+// Render implements Component.
+func (d *Droppable) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "div",
+		Attrs(Attr("", "onPointerUp", d.drop)),
+		props.Children()...,
+	)
+}
+
+func (d *Droppable) drop(args []Value) {
+	if d.enqueue == nil {
+		return
+	}
+	s := d.enqueue.v.drag
+	if s == nil {
+		return
+	}
+	if len(args) > 0 {
+		s.move(args[0])
+	}
+	if fn, ok := d.props["onDrop"].(func(DragEvent)); ok {
+		fn(s.event())
+	}
+}
@@ -0,0 +1,84 @@
+package greact
+
+import "testing"
+
+func pointerEvent(x, y float64) Value {
+	ev := newObject()
+	ev.Set("clientX", x)
+	ev.Set("clientY", y)
+	return ev
+}
+
+func TestDraggable(t *testing.T) {
+	doc := newObject()
+	v := New(WithBackend(Backend{Document: doc, Callbacks: callbacks}))
+	v.Register("draggable", &Draggable{})
+	v.Register("droppable", &Droppable{})
+	var started, dropped []DragEvent
+	h := NewNode
+	node := func(children ...*Node) *Node {
+		return h(ElementNode, "", "div", nil, children...)
+	}
+	drag := h(ElementNode, "", "draggable", Attrs(
+		Attr("", "data", "card-1"),
+		Attr("", "onDragStart", func(e DragEvent) { started = append(started, e) }),
+	), h(ElementNode, "", "span", nil))
+	drop := h(ElementNode, "", "droppable", Attrs(
+		Attr("", "onDrop", func(e DragEvent) { dropped = append(dropped, e) }),
+	), h(ElementNode, "", "ul", nil))
+	el := newObject()
+	div := v.Render(node(drag, drop), el).(*object)
+
+	// touch pointers are released from the element they were pressed on.
+	down := pointerEvent(10, 20)
+	down.Set("pointerId", 7)
+	var released []Value
+	down.Set("target", div.children[0].children[0])
+	div.children[0].children[0].Set("releasePointerCapture", callbacks(func(args []Value) {
+		released = args
+	}))
+	div.children[0].children[0].dispatch("pointerdown", down)
+	if len(released) != 1 || released[0].Int() != 7 {
+		t.Errorf("expected the pointer to be released from the draggable got %v", released)
+	}
+	if len(started) != 1 || started[0].Data != "card-1" || started[0].X != 10 {
+		t.Fatalf("expected drag start at 10 with the payload got %+v", started)
+	}
+	doc.dispatch("pointermove", pointerEvent(30, 40))
+	div.children[1].children[0].dispatch("pointerup", pointerEvent(50, 60))
+	doc.dispatch("pointerup", pointerEvent(50, 60))
+	if len(dropped) != 1 {
+		t.Fatalf("expected one drop got %d", len(dropped))
+	}
+	e := dropped[0]
+	if e.Data != "card-1" || e.X != 50 || e.Y != 60 || e.DX != 40 || e.DY != 40 {
+		t.Errorf("unexpected drop event %+v", e)
+	}
+	for name, l := range doc.listeners {
+		if len(l) != 0 {
+			t.Errorf("expected %s listeners to be removed after the drop", name)
+		}
+	}
+
+	// a pointerup without a drag in progress is not a drop.
+	div.children[1].dispatch("pointerup", pointerEvent(0, 0))
+	if len(dropped) != 1 {
+		t.Errorf("expected no drop without a drag got %d", len(dropped))
+	}
+
+	// unmounting the draggable cancels its drag.
+	div.children[0].dispatch("pointerdown", pointerEvent(0, 0))
+	if len(doc.listeners["pointermove"]) != 1 {
+		t.Fatal("expected the drag to listen to the document")
+	}
+	v.Render(node(drop), el, div)
+	for name, l := range doc.listeners {
+		if len(l) != 0 {
+			t.Errorf("expected %s listeners to be removed on unmount", name)
+		}
+	}
+	div.children[0].dispatch("pointerup", pointerEvent(0, 0))
+	if len(dropped) != 1 {
+		t.Errorf("expected no drop after unmount got %d", len(dropped))
+	}
+}
@@ -0,0 +1,42 @@
+package elements
+
+var mathML = map[string]bool{
+	"annotation":     true,
+	"annotation-xml": true,
+	"maction":        true,
+	"math":           true,
+	"menclose":       true,
+	"merror":         true,
+	"mfenced":        true,
+	"mfrac":          true,
+	"mi":             true,
+	"mmultiscripts":  true,
+	"mn":             true,
+	"mo":             true,
+	"mover":          true,
+	"mpadded":        true,
+	"mphantom":       true,
+	"mprescripts":    true,
+	"mroot":          true,
+	"mrow":           true,
+	"ms":             true,
+	"mspace":         true,
+	"msqrt":          true,
+	"mstyle":         true,
+	"msub":           true,
+	"msubsup":        true,
+	"msup":           true,
+	"mtable":         true,
+	"mtd":            true,
+	"mtext":          true,
+	"mtr":            true,
+	"munder":         true,
+	"munderover":     true,
+	"none":           true,
+	"semantics":      true,
+}
+
+// MathML returns true if the name is a valid MathML element
+func MathML(name string) bool {
+	return mathML[name]
+}
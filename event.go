@@ -0,0 +1,139 @@
+package greact
+
+import "strings"
+
+// Event is the dom event passed to handlers added with On.
+type Event struct {
+	v Value
+}
+
+// On returns the attribute adding handler as the listener for the dom event
+// named event, like click or input.
+//
+// 	greact.On("click", func(e greact.Event) {
+// 		e.PreventDefault()
+// 	})
+//
+// The listener is replaced when handler changes between renders and removed
+// with the attribute.
+func On(event string, handler func(Event)) Attribute {
+	return Attr("", "on"+event, func(args []Value) {
+		var e Event
+		if len(args) > 0 {
+			e.v = args[0]
+		}
+		handler(e)
+	})
+}
+
+// JSValue returns the underlying dom event.
+func (e Event) JSValue() Value {
+	return e.v
+}
+
+// PreventDefault cancels the default action of the event.
+func (e Event) PreventDefault() {
+	if Valid(e.v) {
+		e.v.Call("preventDefault")
+	}
+}
+
+// StopPropagation stops the event from reaching the listeners of ancestors.
+func (e Event) StopPropagation() {
+	if Valid(e.v) {
+		e.v.Call("stopPropagation")
+	}
+}
+
+// Target returns the element the event was dispatched to, or nil.
+func (e Event) Target() Element {
+	if !Valid(e.v) {
+		return nil
+	}
+	if t := e.v.Get("target"); Valid(t) {
+		return t
+	}
+	return nil
+}
+
+// Value returns the value of the target, like the text of an input. It is
+// empty when the target has no string value.
+func (e Event) Value() string {
+	t := e.Target()
+	if t == nil {
+		return ""
+	}
+	if v := t.Get("value"); v.Type() == TypeString {
+		return v.String()
+	}
+	return ""
+}
+
+// listener is an event listener added for an on attribute of an element.
+type listener struct {
+	// target is the element listened to, this is the window for resize
+	// events.
+	target  Element
+	event   string
+	cb      Resource
+	capture bool
+}
+
+// addListener adds fn as the listener for the on attribute name of node,
+// replacing the one added by a previous render. resize events only fire on
+// the window, so onResize listens to the window of the document while node is
+// rendered.
+func (v *Vected) addListener(node Element, name string, fn func([]Value)) {
+	v.removeListener(node, name)
+	l := listener{
+		target:  node,
+		event:   eventName(name),
+		cb:      v.cb(fn),
+		capture: name != strings.TrimSuffix(name, "Capture"),
+	}
+	if l.event == "resize" {
+		if w := v.Document.Get("defaultView"); Valid(w) {
+			l.target = w
+		}
+	}
+	l.target.Call("addEventListener", l.event, l.cb, l.capture)
+	id := elemID(node)
+	if v.listeners[id] == nil {
+		v.listeners[id] = make(map[string]listener)
+	}
+	v.listeners[id][name] = l
+}
+
+// removeListener removes the listener for the on attribute name of node and
+// releases its callback.
+func (v *Vected) removeListener(node Element, name string) {
+	id := node.Get(AttrKey)
+	if id.Type() != TypeNumber {
+		return
+	}
+	m := v.listeners[id.Int()]
+	l, ok := m[name]
+	if !ok {
+		return
+	}
+	l.target.Call("removeEventListener", l.event, l.cb, l.capture)
+	l.cb.Release()
+	delete(m, name)
+	if len(m) == 0 {
+		delete(v.listeners, id.Int())
+	}
+}
+
+// releaseListeners removes all the listeners added to elem and releases their
+// callbacks.
+func (v *Vected) releaseListeners(elem Element) {
+	id := elem.Get(AttrKey)
+	if id.Type() != TypeNumber {
+		return
+	}
+	for _, l := range v.listeners[id.Int()] {
+		l.target.Call("removeEventListener", l.event, l.cb, l.capture)
+		l.cb.Release()
+	}
+	delete(v.listeners, id.Int())
+}
@@ -0,0 +1,127 @@
+package greact
+
+import "testing"
+
+func TestOn(t *testing.T) {
+	v := New(WithBackend(Backend{
+		Document:  newObject(),
+		Callbacks: callbacks,
+	}))
+	var got []string
+	handler := func(name string) func(Event) {
+		return func(e Event) {
+			e.PreventDefault()
+			got = append(got, name+":"+e.Value())
+		}
+	}
+	h := NewNode
+	button := func(attrs ...Attribute) *Node {
+		return h(ElementNode, "", "button", attrs)
+	}
+	el := newObject()
+	b := v.Render(button(On("click", handler("first"))), el).(*object)
+
+	input := newObject()
+	input.Set("value", "hello")
+	ev := newObject()
+	ev.Set("target", input)
+	b.dispatch("click", ev)
+	if len(got) != 1 || got[0] != "first:hello" {
+		t.Fatalf("expected the handler to receive the event got %v", got)
+	}
+	var prevented bool
+	for _, j := range ev.journal {
+		if j[0] == "call" && j[1] == "preventDefault" {
+			prevented = true
+		}
+	}
+	if !prevented {
+		t.Error("expected PreventDefault to call preventDefault")
+	}
+
+	got = nil
+	v.Render(button(On("click", handler("second"))), el, b)
+	b.dispatch("click", ev)
+	if len(got) != 1 || got[0] != "second:hello" {
+		t.Errorf("expected only the new handler to be called got %v", got)
+	}
+	if n := len(b.listeners["click"]); n != 1 {
+		t.Errorf("expected the previous listener to be removed got %d listeners", n)
+	}
+
+	v.Render(button(), el, b)
+	if n := len(b.listeners["click"]); n != 0 {
+		t.Errorf("expected the listener to be removed with the attribute got %d", n)
+	}
+}
+
+func TestVected_releaseListeners(t *testing.T) {
+	v := New(WithBackend(Backend{
+		Document:  newObject(),
+		Callbacks: callbacks,
+	}))
+	h := NewNode
+	list := func(items ...string) *Node {
+		var children []*Node
+		for _, item := range items {
+			children = append(children, h(ElementNode, "", "li", Attrs(
+				Attr("", "key", item),
+				On("click", func(Event) {}),
+			)))
+		}
+		return h(ElementNode, "", "ul", nil, children...)
+	}
+	el := newObject()
+	ul := v.Render(list("a", "b"), el).(*object)
+	a, b := ul.children[0], ul.children[1]
+	first := a.listeners["click"][0]
+	removed := b.listeners["click"][0]
+
+	v.Render(list("a"), el, ul)
+	if !removed.released {
+		t.Error("expected the callback of the removed node to be released")
+	}
+	if !first.released {
+		t.Error("expected the callback of the replaced handler to be released")
+	}
+	if n := len(a.listeners["click"]); n != 1 || a.listeners["click"][0].released {
+		t.Errorf("expected the new handler to be listening got %d listeners", n)
+	}
+	if n := len(v.listeners); n != 1 {
+		t.Errorf("expected the listeners of one node to be tracked got %d", n)
+	}
+
+	v.Unmount(el)
+	if n := len(v.listeners); n != 0 {
+		t.Errorf("expected the listeners to be forgotten on unmount got %d", n)
+	}
+}
+
+func TestOnResizeThrottled(t *testing.T) {
+	doc := newObject()
+	win := newObject()
+	doc.Set("defaultView", win)
+	v := New(WithBackend(Backend{
+		Document:  doc,
+		Callbacks: callbacks,
+		Schedule: func(fn func()) {
+			fn()
+		},
+	}))
+	var calls int
+	el := newObject()
+	div := v.Render(NewNode(ElementNode, "", "div", Attrs(OnResizeThrottled(func([]Value) {
+		calls++
+	}))), el).(*object)
+	if n := len(div.listeners["resize"]); n != 0 {
+		t.Errorf("expected no resize listener on the element got %d", n)
+	}
+	win.dispatch("resize", newObject())
+	if calls != 1 {
+		t.Errorf("expected the handler to be called when the window is resized got %d calls", calls)
+	}
+	v.Unmount(el)
+	if n := len(win.listeners["resize"]); n != 0 {
+		t.Errorf("expected the window listener to be removed with the element got %d", n)
+	}
+}
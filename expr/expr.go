@@ -73,13 +73,27 @@ func (e Expression) QuoteExpr() (ast.Expr, error) {
 //
 // Note that the expression must be valid go expressions.
 func ExtractExpressions(src string, begin, end rune) (result []Expression, err error) {
+	return extract(src, begin, end, true)
+}
+
+// ExtractSegments is like ExtractExpressions but keeps white space of plain
+// text, so the original text can be reconstructed from the returned
+// expressions.
+func ExtractSegments(src string, begin, end rune) (result []Expression, err error) {
+	return extract(src, begin, end, false)
+}
+
+func extract(src string, begin, end rune, trim bool) (result []Expression, err error) {
 	var buf bytes.Buffer
 	line, col, count := 0, 0, 0
 	for _, v := range src {
 		switch v {
 		case begin:
 			if buf.Len() > 0 {
-				txt := strings.TrimSpace(buf.String())
+				txt := buf.String()
+				if trim {
+					txt = strings.TrimSpace(txt)
+				}
 				if txt != "" {
 					result = append(result, Expression{
 						Text:  txt,
@@ -112,7 +126,10 @@ func ExtractExpressions(src string, begin, end rune) (result []Expression, err e
 		}
 	}
 	if buf.Len() > 0 {
-		txt := strings.TrimSpace(buf.String())
+		txt := buf.String()
+		if trim {
+			txt = strings.TrimSpace(txt)
+		}
 		if txt != "" {
 			result = append(result, Expression{
 				Text:  txt,
@@ -47,3 +47,20 @@ func TestExtractExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractSegments(t *testing.T) {
+	src := "btn btn-{a} {b}"
+	expect := []Expression{
+		{Text: "btn btn-", Plain: true},
+		{Text: "a", Plain: false},
+		{Text: " ", Plain: true},
+		{Text: "b", Plain: false},
+	}
+	e, err := ExtractSegments(src, '{', '}')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, expect) {
+		t.Errorf("expected %v got %v", expect, e)
+	}
+}
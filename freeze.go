@@ -0,0 +1,29 @@
+package greact
+
+import "context"
+
+// Freeze is a component that stops its children from being updated while the
+// frozen prop is true.
+//
+// 	<freeze frozen={paused}>
+// 		<chart data={data} />
+// 	</freeze>
+//
+// Parent updates don't reach a frozen subtree, it keeps showing what was
+// rendered when it froze and catches up once frozen is false again. Components
+// inside the subtree can still update themselves with SetState.
+type Freeze struct {
+	Core
+}
+
+// ShouldComponentUpdate implements ShouldUpdate, updates are skipped while
+// frozen.
+func (f *Freeze) ShouldComponentUpdate(ctx context.Context, props Props, state State) bool {
+	frozen, _ := props["frozen"].(bool)
+	return !frozen
+}
+
+// Render implements Component.
+func (f *Freeze) Render(ctx context.Context, props Props, state State) *Node {
+	return Frag(props.Children()...)
+}
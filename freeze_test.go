@@ -0,0 +1,33 @@
+package greact
+
+import "testing"
+
+func TestFreeze(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("freeze", &Freeze{})
+	v.Register("counter", &renderCounter{})
+	h := NewNode
+	node := func(frozen bool, count int) *Node {
+		return h(ElementNode, "", "div", nil,
+			h(ElementNode, "", "freeze", Attrs(Attr("", "frozen", frozen)),
+				h(ElementNode, "", "counter", Attrs(Attr("", "count", count))),
+			),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(false, 0), el).(*object)
+	cmp := v.findComponent(div.children[0]).core().component.(*renderCounter)
+	v.Render(node(true, 1), el, div)
+	v.Render(node(true, 2), el, div)
+	if cmp.renders != 1 {
+		t.Errorf("expected a frozen subtree not to render got %d renders", cmp.renders)
+	}
+	v.Render(node(false, 3), el, div)
+	if cmp.renders != 2 {
+		t.Errorf("expected the subtree to render once unfrozen got %d renders", cmp.renders)
+	}
+	if got := cmp.props["count"]; got != 3 {
+		t.Errorf("expected the latest props got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package greact
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ImageSource is a candidate url for an Image together with the intrinsic
+// width of the image it points to.
+type ImageSource struct {
+	Width int
+	URL   string
+}
+
+// Image is a component that renders a responsive <img> from these props.
+//
+// 	src     string         fallback url for browsers without srcset support
+// 	sources []ImageSource  candidates used to build the srcset attribute
+// 	sizes   string         optional, computed from the source widths if missing
+// 	alt     string         optional
+// 	loading string         optional, defaults to lazy
+//
+// Any other prop is passed to the img element as is.
+type Image struct {
+	Core
+}
+
+// Render implements Component.
+func (i *Image) Render(ctx context.Context, props Props, state State) *Node {
+	var sources []ImageSource
+	if s, ok := props["sources"].([]ImageSource); ok {
+		sources = append(sources, s...)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Width < sources[j].Width
+	})
+	attrs := Props{
+		"loading": "lazy",
+	}
+	for k, v := range props {
+		switch k {
+		case "sources", "children":
+		default:
+			attrs[k] = v
+		}
+	}
+	if len(sources) > 0 {
+		attrs["srcset"] = srcset(sources)
+		if _, ok := attrs["sizes"]; !ok {
+			attrs["sizes"] = sizes(sources)
+		}
+	}
+	return NewNode(ElementNode, "", "img", Attrs(Spread(attrs)))
+}
+
+// srcset returns the value of the srcset attribute for sources.
+func srcset(sources []ImageSource) string {
+	s := make([]string, len(sources))
+	for i, v := range sources {
+		s[i] = fmt.Sprintf("%s %dw", v.URL, v.Width)
+	}
+	return strings.Join(s, ", ")
+}
+
+// sizes returns a sizes attribute that picks the smallest source that is at
+// least as wide as the viewport, sources must be sorted by width.
+func sizes(sources []ImageSource) string {
+	s := make([]string, len(sources))
+	for i, v := range sources {
+		if i == len(sources)-1 {
+			s[i] = fmt.Sprintf("%dpx", v.Width)
+		} else {
+			s[i] = fmt.Sprintf("(max-width: %dpx) %dpx", v.Width, v.Width)
+		}
+	}
+	return strings.Join(s, ", ")
+}
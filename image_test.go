@@ -0,0 +1,44 @@
+package greact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("image", &Image{})
+	el := newObject()
+	img := v.Render(NewNode(ElementNode, "", "image", Attrs(
+		Attr("", "src", "cat.jpg"),
+		Attr("", "alt", "a cat"),
+		Attr("", "sources", []ImageSource{
+			{Width: 1280, URL: "cat-1280.jpg"},
+			{Width: 320, URL: "cat-320.jpg"},
+			{Width: 640, URL: "cat-640.jpg"},
+		}),
+	)), el).(*object)
+	if img.name != "img" {
+		t.Fatalf("expected img got %s", img.name)
+	}
+	attr := func(name string) string {
+		return img.Call("getAttribute", name).String()
+	}
+	srcset := attr("srcset")
+	for _, w := range []string{"cat-320.jpg 320w", "cat-640.jpg 640w", "cat-1280.jpg 1280w"} {
+		if !strings.Contains(srcset, w) {
+			t.Errorf("expected srcset to contain %s got %s", w, srcset)
+		}
+	}
+	expect := "(max-width: 320px) 320px, (max-width: 640px) 640px, 1280px"
+	if got := attr("sizes"); got != expect {
+		t.Errorf("expected sizes %s got %s", expect, got)
+	}
+	if got := attr("loading"); got != "lazy" {
+		t.Errorf("expected lazy loading got %s", got)
+	}
+	if got := attr("src"); got != "cat.jpg" {
+		t.Errorf("expected src cat.jpg got %s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package greact
+
+// IslandAttr is the attribute marking the root element of an island in server
+// rendered markup.
+const IslandAttr = "data-vected-island"
+
+// Island marks node as an interactive island identified by id. It returns a
+// copy of node with the IslandAttr attribute added, node must be an element
+// and is left unchanged.
+//
+// Render the whole page on the server and use Hydrate on the client to attach
+// components and event listeners to islands only, the rest of the page is left
+// as static html.
+func Island(id string, node *Node) *Node {
+	return withAttrs(node, Attr("", IslandAttr, id))
+}
+
+// IslandHydrateAttr is the attribute telling when an island is hydrated, an
+// island is hydrated by Hydrate unless the value is HydrateOnVisible.
+const IslandHydrateAttr = "data-vected-hydrate"
+
+// HydrateOnVisible is the IslandHydrateAttr value of islands that are only
+// hydrated once they scroll into view.
+const HydrateOnVisible = "visible"
+
+// VisibleIsland is like Island but the island is hydrated when it becomes
+// visible, which saves work for islands below the fold. This needs a backend
+// with a VisibilityObserver, otherwise the island is hydrated right away.
+func VisibleIsland(id string, node *Node) *Node {
+	return withAttrs(node,
+		Attr("", IslandHydrateAttr, HydrateOnVisible),
+		Attr("", IslandAttr, id),
+	)
+}
+
+// withAttrs returns a shallow copy of node with attrs appended to a copy of
+// its attributes.
+func withAttrs(node *Node, attrs ...Attribute) *Node {
+	n := *node
+	n.Attr = make([]Attribute, 0, len(node.Attr)+len(attrs))
+	n.Attr = append(append(n.Attr, node.Attr...), attrs...)
+	return &n
+}
+
+// islandID returns the id of the island node is the root of.
+func islandID(node *Node) (string, bool) {
+	if node.Type != ElementNode {
+		return "", false
+	}
+	for _, a := range node.Attr {
+		if a.Key == IslandAttr {
+			id, ok := a.Val.(string)
+			return id, ok
+		}
+	}
+	return "", false
+}
+
+// onVisible returns true if node is an island hydrated when visible.
+func onVisible(node *Node) bool {
+	for _, a := range node.Attr {
+		if a.Key == IslandHydrateAttr {
+			return a.Val == HydrateOnVisible
+		}
+	}
+	return false
+}
+
+// Hydrate hydrates islands found in vnode against the server rendered markup
+// in root. Every island is rendered into the element in root that has the same
+// IslandAttr, parts of vnode outside islands are ignored and the matching dom
+// is never touched.
+//
+// Islands nested in other islands are hydrated with their parent. Islands
+// created with VisibleIsland are hydrated when the VisibilityObserver of the
+// backend reports them visible.
+//
+// Components outside islands are not rendered, so only islands present in
+// vnode itself are found. An island built inside the Render method of a
+// component is not hydrated, mark the component's node as the island instead.
+func (v *Vected) Hydrate(vnode *Node, root Element) {
+	id, ok := islandID(vnode)
+	if !ok {
+		for _, ch := range vnode.Children {
+			v.Hydrate(ch, root)
+		}
+		return
+	}
+	if elem := findIsland(root, id); Valid(elem) {
+		hydrate := func() {
+			v.Render(vnode, elem.Get("parentNode"), elem)
+		}
+		if v.visibility != nil && onVisible(vnode) {
+			v.visibility(elem, hydrate)
+		} else {
+			hydrate()
+		}
+	}
+}
+
+// findIsland returns the element in the tree rooted at elem whose IslandAttr
+// is id.
+func findIsland(elem Element, id string) Element {
+	if elem.Get("nodeType").Int() != elementNodeType {
+		return nil
+	}
+	if a := elem.Call("getAttribute", IslandAttr); Valid(a) && a.String() == id {
+		return elem
+	}
+	for child := elem.Get("firstChild"); Valid(child); child = child.Get("nextSibling") {
+		if found := findIsland(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
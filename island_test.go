@@ -0,0 +1,123 @@
+package greact
+
+import "testing"
+
+func TestVected_Hydrate(t *testing.T) {
+	doc := newObject()
+	el := func(name string, children ...Element) Element {
+		e := doc.Call("createElement", name)
+		for _, ch := range children {
+			e.Call("appendChild", ch)
+		}
+		return e
+	}
+	static := el("button", doc.Call("createTextNode", "static"))
+	interactive := el("button", doc.Call("createTextNode", "interactive"))
+	interactive.Call("setAttribute", IslandAttr, "counter")
+	root := el("div", static, el("section", interactive))
+
+	v := New()
+	v.Document = doc
+	var listeners int
+	v.cb = func(fn func([]Value)) Resource {
+		listeners++
+		return release{}
+	}
+	click := func([]Value) {}
+	h := NewNode
+	v.Hydrate(h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "button", Attrs(Attr("", "onClick", click)),
+			h(TextNode, "", "static", nil),
+		),
+		h(ElementNode, "", "section", nil,
+			Island("counter", h(ElementNode, "", "button", Attrs(Attr("", "onClick", click)),
+				h(TextNode, "", "interactive", nil),
+			)),
+		),
+	), root)
+
+	hasListener := func(e Element) bool {
+		for _, j := range e.(*object).journal {
+			if j[0] == "call" && j[1] == "addEventListener" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasListener(static) {
+		t.Error("expected no listener outside islands")
+	}
+	if !hasListener(interactive) {
+		t.Error("expected the island to be hydrated")
+	}
+	if listeners == 0 {
+		t.Error("expected callbacks to be created for the island")
+	}
+	if got := root.(*object).children[1].children[0]; got != interactive {
+		t.Error("expected the island element to be reused")
+	}
+}
+
+func TestVected_HydrateOnVisible(t *testing.T) {
+	doc := newObject()
+	root := doc.Call("createElement", "div")
+	for _, id := range []string{"eager", "lazy"} {
+		b := doc.Call("createElement", "button")
+		b.Call("setAttribute", IslandAttr, id)
+		root.Call("appendChild", b)
+	}
+	var visible []func()
+	v := New(WithBackend(Backend{
+		Document: doc,
+		Callbacks: func(fn func([]Value)) Resource {
+			return release{}
+		},
+		Visibility: func(elem Element, fn func()) {
+			visible = append(visible, fn)
+		},
+	}))
+	click := func([]Value) {}
+	h := NewNode
+	v.Hydrate(h(ElementNode, "", "div", nil,
+		Island("eager", h(ElementNode, "", "button", Attrs(Attr("", "onClick", click)))),
+		VisibleIsland("lazy", h(ElementNode, "", "button", Attrs(Attr("", "onClick", click)))),
+	), root)
+
+	hydrated := func(e Element) bool {
+		return Valid(e.Get(AttrKey))
+	}
+	children := root.(*object).children
+	if !hydrated(children[0]) {
+		t.Error("expected the eager island to be hydrated")
+	}
+	if hydrated(children[1]) {
+		t.Fatal("expected the lazy island to wait until visible")
+	}
+	if len(visible) != 1 {
+		t.Fatalf("expected one observed island got %d", len(visible))
+	}
+	visible[0]()
+	if !hydrated(children[1]) {
+		t.Error("expected the lazy island to be hydrated once visible")
+	}
+}
+
+func TestIsland(t *testing.T) {
+	attrs := make([]Attribute, 1, 2)
+	attrs[0] = Attr("", "id", "main")
+	node := NewNode(ElementNode, "", "div", attrs)
+	a := Island("a", node)
+	b := VisibleIsland("b", node)
+	if len(node.Attr) != 1 {
+		t.Errorf("expected node to be left unchanged got %v", node.Attr)
+	}
+	if id, _ := islandID(a); id != "a" {
+		t.Errorf("expected island a got %q", id)
+	}
+	if id, _ := islandID(b); id != "b" || !onVisible(b) {
+		t.Errorf("expected visible island b got %q", id)
+	}
+	if onVisible(a) {
+		t.Error("expected island a not to share attributes with b")
+	}
+}
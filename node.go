@@ -1,5 +1,13 @@
 package greact
 
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
 // A NodeType is the type of a Node.
 type NodeType uint32
 
@@ -11,6 +19,13 @@ const (
 	ElementNode
 	CommentNode
 	DoctypeNode
+
+	// FragmentNode groups its children without rendering an element of its
+	// own, see Frag.
+	FragmentNode
+
+	// PortalNode renders its only child into another container, see Portal.
+	PortalNode
 )
 
 func (n NodeType) String() string {
@@ -27,6 +42,10 @@ func (n NodeType) String() string {
 		return "CommentNode"
 	case DoctypeNode:
 		return "DoctypeNode"
+	case FragmentNode:
+		return "FragmentNode"
+	case PortalNode:
+		return "PortalNode"
 	default:
 		return "ErrorNode"
 	}
@@ -47,41 +66,281 @@ type Node struct {
 	Children  []*Node
 }
 
+// jsonNode is the json representation of Node.
+type jsonNode struct {
+	Type      NodeType    `json:"type"`
+	Data      string      `json:"data,omitempty"`
+	Namespace string      `json:"ns,omitempty"`
+	Attr      []Attribute `json:"attr,omitempty"`
+	Children  []*Node     `json:"children,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNode(*v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Node) UnmarshalJSON(data []byte) error {
+	var n jsonNode
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*v = Node(n)
+	return nil
+}
+
+// jsonAttribute is the json representation of Attribute. Val is tagged with its
+// type so it is decoded to the same go type.
+type jsonAttribute struct {
+	Namespace string          `json:"ns,omitempty"`
+	Key       string          `json:"key"`
+	Type      string          `json:"type"`
+	Val       json.RawMessage `json:"val,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Values of type string, bool, int and
+// float64 are preserved, other values are encoded with encoding/json and
+// decoded back as generic json values. Functions and channels can't be
+// marshaled.
+func (a Attribute) MarshalJSON() ([]byte, error) {
+	o := jsonAttribute{Namespace: a.Namespace, Key: a.Key}
+	switch a.Val.(type) {
+	case nil:
+		o.Type = "null"
+	case string:
+		o.Type = "string"
+	case bool:
+		o.Type = "bool"
+	case int:
+		o.Type = "int"
+	case float64:
+		o.Type = "float"
+	default:
+		switch reflect.TypeOf(a.Val).Kind() {
+		case reflect.Func, reflect.Chan:
+			return nil, fmt.Errorf("greact: can't marshal %T value of attribute %s", a.Val, a.Key)
+		}
+		o.Type = "json"
+	}
+	if a.Val != nil {
+		b, err := json.Marshal(a.Val)
+		if err != nil {
+			return nil, err
+		}
+		o.Val = b
+	}
+	return json.Marshal(o)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	var o jsonAttribute
+	if err := json.Unmarshal(data, &o); err != nil {
+		return err
+	}
+	a.Namespace = o.Namespace
+	a.Key = o.Key
+	a.Val = nil
+	var err error
+	switch o.Type {
+	case "null":
+	case "string":
+		var v string
+		err = json.Unmarshal(o.Val, &v)
+		a.Val = v
+	case "bool":
+		var v bool
+		err = json.Unmarshal(o.Val, &v)
+		a.Val = v
+	case "int":
+		var v int
+		err = json.Unmarshal(o.Val, &v)
+		a.Val = v
+	case "float":
+		var v float64
+		err = json.Unmarshal(o.Val, &v)
+		a.Val = v
+	case "json":
+		var v interface{}
+		err = json.Unmarshal(o.Val, &v)
+		a.Val = v
+	default:
+		err = fmt.Errorf("greact: unknown attribute value type %q", o.Type)
+	}
+	return err
+}
+
+// nodePool holds nodes put back with ReleaseNode or by a Vected with
+// PoolNodes enabled, NewNode reuses them before allocating new ones.
+var nodePool = &sync.Pool{}
+
 // NewNode is a wrapper for creating new node
 func NewNode(typ NodeType, ns, name string, attrs []Attribute, children ...*Node) *Node {
+	return buildNode(true, typ, ns, name, attrs, children...)
+}
+
+// NewNodeUnmerged is like NewNode but keeps adjacent text children as distinct
+// text nodes. Use it when the positions of text nodes matter, for instance to
+// match text nodes of server markup when hydrating.
+func NewNodeUnmerged(typ NodeType, ns, name string, attrs []Attribute, children ...*Node) *Node {
+	return buildNode(false, typ, ns, name, attrs, children...)
+}
+
+func buildNode(mergeText bool, typ NodeType, ns, name string, attrs []Attribute, children ...*Node) *Node {
+	if n, ok := nodePool.Get().(*Node); ok {
+		n.Type = typ
+		n.Namespace = ns
+		n.Data = name
+		n.Attr = append(n.Attr[:0], attrs...)
+		n.Children = appendChildren(n.Children[:0], mergeText, children...)
+		return n
+	}
 	return &Node{
 		Type:      typ,
 		Namespace: ns,
 		Data:      name,
 		Attr:      attrs,
-		Children:  newChildren(children...),
+		Children:  appendChildren(nil, mergeText, children...),
+	}
+}
+
+// ReleaseNode resets n and all of its descendants and puts them back to the
+// pool used by NewNode. n must not be used after calling this.
+func ReleaseNode(n *Node) {
+	releaseNode(n, make(map[*Node]bool))
+}
+
+func releaseNode(n *Node, seen map[*Node]bool) {
+	if n == nil || seen[n] {
+		return
+	}
+	seen[n] = true
+	for _, ch := range n.Children {
+		releaseNode(ch, seen)
+	}
+	resetNode(n)
+}
+
+// resetNode clears n and puts it back to the pool without touching its
+// children.
+func resetNode(n *Node) {
+	for i := range n.Children {
+		n.Children[i] = nil
+	}
+	for i := range n.Attr {
+		n.Attr[i] = Attribute{}
+	}
+	n.Type = ErrorNode
+	n.Namespace = ""
+	n.Data = ""
+	n.Attr = n.Attr[:0]
+	n.Children = n.Children[:0]
+	nodePool.Put(n)
+}
+
+// Empty returns a node that renders nothing. It is rendered as an empty
+// comment which keeps the position of the missing node in the dom, use it for
+// conditional rendering so siblings are not shifted when the condition
+// changes.
+//
+// 	if !props["open"].(bool) {
+// 		child = greact.Empty()
+// 	}
+func Empty() *Node {
+	return &Node{Type: CommentNode}
+}
+
+// Frag returns a fragment of children. Fragments are transparent, their
+// children take the place of the fragment among its siblings, so a component
+// can return several nodes without a wrapper element.
+//
+// 	return greact.Frag(title, body, footer)
+//
+// Nested fragments are flattened. The root of a tree, returned by the Render
+// method of a component or passed to Vected.Render, is a single dom node. A
+// fragment there with a single child renders the child, and one with several
+// children renders them inside a greact-fragment element styled with
+// display: contents, so the wrapper doesn't affect the layout. Selectors
+// matching direct children, like ul > li, see the wrapper.
+func Frag(children ...*Node) *Node {
+	return &Node{Type: FragmentNode, Children: appendChildren(nil, true, children...)}
+}
+
+// flatten returns nodes with the children of fragments in place of the
+// fragments. nodes is returned as is when there is no fragment.
+func flatten(nodes []*Node) []*Node {
+	for i, n := range nodes {
+		if n != nil && n.Type == FragmentNode {
+			o := append([]*Node{}, nodes[:i]...)
+			return appendFlat(o, nodes[i:])
+		}
+	}
+	return nodes
+}
+
+// fragmentTag is the name of the element holding the children of a fragment
+// with several children at the root of a tree.
+const fragmentTag = "greact-fragment"
+
+// fragmentRoot returns the node rendered in place of fragment n at the root of
+// a tree. This is its only child, Empty when it has none or a fragmentTag
+// element holding its children. n is returned when it isn't a fragment.
+func fragmentRoot(n *Node) *Node {
+	if n == nil || n.Type != FragmentNode {
+		return n
+	}
+	children := flatten(n.Children)
+	switch len(children) {
+	case 0:
+		return Empty()
+	case 1:
+		return children[0]
+	}
+	return &Node{
+		Type:     ElementNode,
+		Data:     fragmentTag,
+		Attr:     []Attribute{{Key: "style", Val: "display: contents"}},
+		Children: children,
 	}
 }
 
-// newChildren processes n nodes.
+func appendFlat(o []*Node, nodes []*Node) []*Node {
+	for _, n := range nodes {
+		if n != nil && n.Type == FragmentNode {
+			o = appendFlat(o, n.Children)
+		} else {
+			o = append(o, n)
+		}
+	}
+	return o
+}
+
+// appendChildren processes n nodes and appends them to o.
 //
-// Adjacent text nodes are merged.
-func newChildren(n ...*Node) []*Node {
-	if len(n) > 0 {
-		var o []*Node
-		var lastText *Node
-		for _, v := range n {
-			switch v.Type {
-			case TextNode:
-				if lastText != nil {
-					lastText.Data += v.Data
-				} else {
-					lastText = v
-					o = append(o, lastText)
-				}
-			default:
-				lastText = nil
-				o = append(o, v)
+// Adjacent text nodes are merged when mergeText is true, fragments are
+// flattened.
+func appendChildren(o []*Node, mergeText bool, n ...*Node) []*Node {
+	n = flatten(n)
+	if !mergeText {
+		return append(o, n...)
+	}
+	var lastText *Node
+	for _, v := range n {
+		switch v.Type {
+		case TextNode:
+			if lastText != nil {
+				lastText.Data += v.Data
+			} else {
+				lastText = v
+				o = append(o, lastText)
 			}
+		default:
+			lastText = nil
+			o = append(o, v)
 		}
-		return o
 	}
-	return nil
+	return o
 }
 
 // Attr returns Attribute from the arguments. This doesn't do much appart from
@@ -97,10 +356,84 @@ func Attr(ns, key string, val interface{}) Attribute {
 // Attrs is a wrapper/shortcut for optionally providing Attributes. Due tto the
 // nature of composition for components, this saves space and improves
 // readability.
+//
+// Attributes created with Spread are expanded in place.
 func Attrs(attr ...Attribute) []Attribute {
+	for i, a := range attr {
+		if a.Key == spreadKey {
+			return spread(attr, i)
+		}
+	}
 	return attr
 }
 
+// spreadKey is the key of attributes returned by Spread.
+const spreadKey = "..."
+
+// Spread returns an Attribute that is expanded by Attrs to an attribute for
+// every key of props, in sorted order. props can be Props or
+// map[string]interface{}, anything else expands to nothing.
+//
+// This is what templates compile {...props["rest"]} to.
+func Spread(props interface{}) Attribute {
+	return Attribute{Key: spreadKey, Val: props}
+}
+
+// spread expands spread attributes in attr starting from the one at i.
+func spread(attr []Attribute, i int) []Attribute {
+	o := append([]Attribute{}, attr[:i]...)
+	for _, a := range attr[i:] {
+		if a.Key != spreadKey {
+			o = append(o, a)
+			continue
+		}
+		var m map[string]interface{}
+		switch e := a.Val.(type) {
+		case Props:
+			m = e
+		case map[string]interface{}:
+			m = e
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			o = append(o, Attr("", k, m[k]))
+		}
+	}
+	return o
+}
+
+// Walk calls fn for v and its descendants in pre-order. Walking stops as soon
+// as fn returns false. Walk returns false if it was stopped.
+func (v *Node) Walk(fn func(*Node) bool) bool {
+	if !fn(v) {
+		return false
+	}
+	for _, ch := range v.Children {
+		if !ch.Walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first node in pre-order, starting from v, for which fn
+// returns true. This returns nil if there is no match.
+func (v *Node) Find(fn func(*Node) bool) *Node {
+	var found *Node
+	v.Walk(func(n *Node) bool {
+		if fn(n) {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
 // Key returns the value of the key attribute of the node as a string. Key
 // attributes can be set to allow easily identifying lists nodes for faster re
 // re rendering.
@@ -1,6 +1,14 @@
 package greact
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestVNode(t *testing.T) {
 	h := NewNode
@@ -22,4 +30,287 @@ func TestVNode(t *testing.T) {
 			t.Errorf("expected %s got %s", txt, x.Children[0].Data)
 		}
 	})
+	t.Run("keeps adjacent text nodes when merging is disabled", func(ts *testing.T) {
+		x := NewNodeUnmerged(ElementNode, "", "foo", nil,
+			h(TextNode, "", "one", nil),
+			h(TextNode, "", "two", nil),
+		)
+		if len(x.Children) != 2 {
+			ts.Fatalf("expected 2 children got %d", len(x.Children))
+		}
+		if x.Children[0].Data != "one" || x.Children[1].Data != "two" {
+			ts.Errorf("expected one and two got %s and %s", x.Children[0].Data, x.Children[1].Data)
+		}
+	})
+}
+
+func TestNodePool(t *testing.T) {
+	h := NewNode
+	t.Run("released nodes are reset", func(ts *testing.T) {
+		n := h(ElementNode, "", "div", Attrs(Attr("", "id", "a")),
+			h(ElementNode, "", "span", nil),
+		)
+		child := n.Children[0]
+		ReleaseNode(n)
+		if n.Data != "" || len(n.Attr) != 0 || len(n.Children) != 0 {
+			ts.Errorf("expected node to be reset got %#v", n)
+		}
+		if child.Data != "" {
+			ts.Errorf("expected child node to be reset got %#v", child)
+		}
+	})
+	t.Run("pooled nodes don't leak previous values", func(ts *testing.T) {
+		for i := 0; i < 10; i++ {
+			ReleaseNode(h(ElementNode, "", "div", Attrs(Attr("", "id", "a")),
+				h(TextNode, "", "hello", nil),
+			))
+			n := h(ElementNode, "", "p", nil)
+			if n.Data != "p" || len(n.Attr) != 0 || len(n.Children) != 0 {
+				ts.Fatalf("expected a clean node got %#v", n)
+			}
+		}
+	})
+}
+
+func benchmarkNodes(b *testing.B, pool bool) {
+	h := NewNode
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		n := h(ElementNode, "", "ul", nil,
+			h(ElementNode, "", "li", nil, h(TextNode, "", "one", nil)),
+			h(ElementNode, "", "li", nil, h(TextNode, "", "two", nil)),
+			h(ElementNode, "", "li", nil, h(TextNode, "", "three", nil)),
+		)
+		if pool {
+			ReleaseNode(n)
+		}
+	}
+}
+
+// pooledTree renders a div of its own around a freeze child component, and
+// keeps the nodes it returned.
+type pooledTree struct {
+	Core
+	divs, freezes []*Node
+}
+
+func (p *pooledTree) Render(ctx context.Context, props Props, state State) *Node {
+	h := NewNode
+	freeze := h(ElementNode, "", "freeze", nil,
+		h(ElementNode, "", "span", nil, h(TextNode, "", fmt.Sprint(state["n"]), nil)),
+	)
+	div := h(ElementNode, "", "div", nil, freeze)
+	p.divs = append(p.divs, div)
+	p.freezes = append(p.freezes, freeze)
+	return div
+}
+
+func TestVected_PoolNodes(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.PoolNodes = true
+	v.Register("tree", &pooledTree{})
+	v.Register("freeze", &Freeze{})
+	div := v.Render(NewNode(ElementNode, "", "tree", nil), newObject())
+	cmp := v.findComponent(div).(*pooledTree)
+	cmp.SetStateMode(Sync, State{"n": 1})
+	if len(cmp.divs) != 2 {
+		t.Fatalf("expected 2 renders got %d", len(cmp.divs))
+	}
+	if got := cmp.divs[0].Data; got != "" {
+		t.Errorf("expected the replaced div to be released got %q", got)
+	}
+	freeze := cmp.freezes[0]
+	if freeze.Data != "freeze" || len(freeze.Children) != 1 {
+		t.Fatalf("expected the child component node to be kept got %#v", freeze)
+	}
+	if span := freeze.Children[0]; span.Data != "span" || span.Children[0].Data != "<nil>" {
+		t.Errorf("expected the child component's children to be kept got %#v", span)
+	}
+}
+
+func BenchmarkNewNode(b *testing.B) {
+	b.Run("alloc", func(b *testing.B) { benchmarkNodes(b, false) })
+	b.Run("pool", func(b *testing.B) { benchmarkNodes(b, true) })
+}
+
+func TestSpread(t *testing.T) {
+	attrs := Attrs(
+		Attr("", "id", "main"),
+		Spread(Props{"title": "hello", "class": "box"}),
+		Spread(nil),
+		Attr("", "key", "a"),
+	)
+	var got []string
+	for _, a := range attrs {
+		got = append(got, fmt.Sprintf("%s=%v", a.Key, a.Val))
+	}
+	expect := "id=main class=box title=hello key=a"
+	if strings.Join(got, " ") != expect {
+		t.Errorf("expected %s got %s", expect, strings.Join(got, " "))
+	}
+}
+
+func TestNode_Walk(t *testing.T) {
+	h := NewNode
+	n := h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "p", nil, h(TextNode, "", "one", nil)),
+		h(ElementNode, "", "ul", nil,
+			h(ElementNode, "", "li", nil),
+			h(ElementNode, "", "li", nil),
+		),
+		h(TextNode, "", "two", nil),
+	)
+	var elements int
+	n.Walk(func(n *Node) bool {
+		if n.Type == ElementNode {
+			elements++
+		}
+		return true
+	})
+	if elements != 5 {
+		t.Errorf("expected 5 elements got %d", elements)
+	}
+	var visited []string
+	complete := n.Walk(func(n *Node) bool {
+		visited = append(visited, n.Data)
+		return n.Data != "ul"
+	})
+	if complete {
+		t.Error("expected walk to be stopped")
+	}
+	if got := strings.Join(visited, ","); got != "div,p,one,ul" {
+		t.Errorf("expected div,p,one,ul got %s", got)
+	}
+}
+
+func TestNode_Find(t *testing.T) {
+	h := NewNode
+	n := h(ElementNode, "", "ul", nil,
+		h(ElementNode, "", "li", Attrs(Attr("", "key", "a")), h(TextNode, "", "a", nil)),
+		h(ElementNode, "", "li", Attrs(Attr("", "key", "b")), h(TextNode, "", "b", nil)),
+	)
+	byKey := func(key string) func(*Node) bool {
+		return func(n *Node) bool {
+			return n.Key() == key
+		}
+	}
+	found := n.Find(byKey("b"))
+	if found == nil || found.Children[0].Data != "b" {
+		t.Errorf("expected node with key b got %v", found)
+	}
+	if n.Find(byKey("c")) != nil {
+		t.Error("expected no node with key c")
+	}
+}
+
+func TestNode_JSON(t *testing.T) {
+	h := NewNode
+	n := h(ElementNode, "", "div", Attrs(
+		Attr("", "id", "main"),
+		Attr("", "hidden", true),
+		Attr("", "tabindex", 2),
+		Attr("", "opacity", 0.5),
+		Attr("", "data", map[string]interface{}{"a": "b"}),
+		Attr("", "empty", nil),
+	),
+		h(TextNode, "", "hello", nil),
+		h(ElementNode, SVGNamespace, "svg", nil,
+			h(ElementNode, SVGNamespace, "use", Attrs(Attr("xlink", "href", "#icon"))),
+		),
+		Empty(),
+	)
+	b, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Node
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, n) {
+		t.Errorf("expected %#v got %#v", n, &got)
+	}
+	_, err = json.Marshal(h(ElementNode, "", "button", Attrs(Attr("", "onClick", func() {}))))
+	if err == nil {
+		t.Error("expected an error marshaling a function")
+	}
+}
+
+func TestFrag(t *testing.T) {
+	h := NewNode
+	li := func(key string) *Node {
+		return h(ElementNode, "", "li", Attrs(Attr("", "key", key)),
+			h(TextNode, "", key, nil),
+		)
+	}
+	v := New()
+	v.Document = newObject()
+	el := newObject()
+	ul := v.Render(h(ElementNode, "", "ul", nil,
+		li("a"),
+		Frag(li("b"), Frag(li("c"), li("d"))),
+		li("e"),
+	), el).(*object)
+	var buf bytes.Buffer
+	renderObject(&buf, ul)
+	expect := "<ul><li>a</li><li>b</li><li>c</li><li>d</li><li>e</li></ul>"
+	if buf.String() != expect {
+		t.Errorf("expected %s got %s", expect, buf.String())
+	}
+
+	// nodes built without NewNode are flattened while diffing.
+	c := ul.children[2]
+	v.Render(&Node{Type: ElementNode, Data: "ul", Children: []*Node{
+		li("e"),
+		{Type: FragmentNode, Children: []*Node{li("c"), li("a")}},
+	}}, el, ul)
+	buf.Reset()
+	renderObject(&buf, ul)
+	expect = "<ul><li>e</li><li>c</li><li>a</li></ul>"
+	if buf.String() != expect {
+		t.Errorf("expected %s got %s", expect, buf.String())
+	}
+	if ul.children[1] != c {
+		t.Error("expected keyed children inside fragments to be reused")
+	}
+
+	p := v.Render(Frag(h(ElementNode, "", "p", nil)), newObject()).(*object)
+	if p.name != "p" {
+		t.Errorf("expected a fragment with a single child to render the child got %s", p.name)
+	}
+
+	// components can return several children.
+	v.Register("freeze", &Freeze{})
+	freeze := func(keys ...string) *Node {
+		var children []*Node
+		for _, k := range keys {
+			children = append(children, li(k))
+		}
+		return h(ElementNode, "", "ul", nil,
+			h(ElementNode, "", "freeze", nil, children...),
+		)
+	}
+	ul = v.Render(freeze("a", "b"), el, ul).(*object)
+	wrapper := ul.children[0]
+	b := wrapper.children[1]
+	v.Render(freeze("b", "a", "c"), el, ul)
+	var got []string
+	for _, ch := range wrapper.children {
+		got = append(got, ch.children[0].nodeValue)
+	}
+	if wrapper.name != "greact-fragment" || strings.Join(got, ",") != "b,a,c" {
+		t.Errorf("expected the children in a greact-fragment got %s %v", wrapper.name, got)
+	}
+	if ul.children[0] != wrapper || wrapper.children[0] != b {
+		t.Error("expected the children of the fragment to be updated in place")
+	}
+	html, err := v.RenderToString(context.Background(), freeze("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `<ul data-__vected_attr__="`) ||
+		!strings.Contains(html, `><greact-fragment style="display: contents"`) {
+		t.Errorf("expected the server to wrap the children like the client got %s", html)
+	}
 }
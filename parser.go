@@ -9,6 +9,8 @@ import (
 	"go/printer"
 	"go/token"
 	"io"
+	"io/ioutil"
+	"strconv"
 	"strings"
 
 	"github.com/gernest/greact/expr"
@@ -52,14 +54,20 @@ func ToNode(n *html.Node) *Node {
 // representation. r must be reading from a subset of xml/html document that is
 // going to processed and compiled to *Node.
 func Parse(r io.Reader) (*Node, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src, spreads := extractSpreads(string(b))
 	base := root()
-	n, err := html.ParseFragment(r, base)
+	n, err := html.ParseFragment(strings.NewReader(src), base)
 	if err != nil {
 		return nil, err
 	}
 	var rst []*Node
 	for _, v := range n {
 		node := ToNode(v)
+		applySpreads(node, spreads)
 		if node.Type == TextNode && strings.TrimSpace(node.Data) == "" {
 			continue
 		}
@@ -80,6 +88,164 @@ func Parse(r io.Reader) (*Node, error) {
 	}
 }
 
+// ParseStrict is like Parse but returns an error for markup with unclosed tags
+// or end tags that don't match the last opened element, instead of letting the
+// html parser silently fix them.
+func ParseStrict(r io.Reader) (*Node, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(string(b)); err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+// validate checks that every element in src is closed in the right order.
+// Errors are reported with the line and column of the offending tag.
+func validate(src string) error {
+	src, _ = extractSpreads(src)
+	type open struct {
+		name      string
+		line, col int
+	}
+	var stack []open
+	line, col := 1, 1
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		tagLine, tagCol := line, col
+		for _, c := range string(z.Raw()) {
+			if c == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return z.Err()
+			}
+			if len(stack) > 0 {
+				last := stack[len(stack)-1]
+				return fmt.Errorf("%d:%d: unclosed <%s>", last.line, last.col, last.name)
+			}
+			return nil
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				stack = append(stack, open{string(name), tagLine, tagCol})
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if voidElements[string(name)] {
+				continue
+			}
+			if len(stack) == 0 {
+				return fmt.Errorf("%d:%d: unexpected </%s>", tagLine, tagCol, name)
+			}
+			last := stack[len(stack)-1]
+			if last.name != string(name) {
+				return fmt.Errorf("%d:%d: unexpected </%s>, <%s> opened at %d:%d is not closed",
+					tagLine, tagCol, name, last.name, last.line, last.col)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// spreadAttr is the prefix of placeholder attributes standing for spread
+// attributes while the template is parsed as html.
+const spreadAttr = "data-vected-spread-"
+
+// extractSpreads replaces spread attributes {...expr} found inside tags with
+// placeholder attributes, the html parser can't handle them. The expressions
+// are returned in the order they appear in src.
+func extractSpreads(src string) (string, []string) {
+	var buf bytes.Buffer
+	var spreads []string
+	var inTag bool
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case !inTag:
+			inTag = c == '<'
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			inTag = false
+		case c == '{' && strings.HasPrefix(src[i:], "{..."):
+			end := closingBrace(src[i:])
+			if end == -1 {
+				break
+			}
+			spreads = append(spreads, strings.TrimSpace(src[i+4:i+end]))
+			fmt.Fprintf(&buf, "%s%d", spreadAttr, len(spreads)-1)
+			i += end
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String(), spreads
+}
+
+// closingBrace returns the index of the } closing the { s starts with, or -1
+// when it is not closed. Braces nested in the expression, like the ones of
+// composite and func literals, are matched and braces inside string and rune
+// literals are ignored.
+func closingBrace(s string) int {
+	var depth int
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// applySpreads replaces placeholder attributes created by extractSpreads in
+// node and its children with spread attributes.
+func applySpreads(node *Node, spreads []string) {
+	if len(spreads) == 0 {
+		return
+	}
+	for i, v := range node.Attr {
+		if strings.HasPrefix(v.Key, spreadAttr) {
+			idx, err := strconv.Atoi(strings.TrimPrefix(v.Key, spreadAttr))
+			if err == nil && idx < len(spreads) {
+				node.Attr[i] = Attribute{Key: spreadKey, Val: spreads[idx]}
+			}
+		}
+	}
+	for _, ch := range node.Children {
+		applySpreads(ch, spreads)
+	}
+}
+
 func root() *html.Node {
 	return &html.Node{
 		DataAtom: atom.Div,
@@ -124,41 +290,35 @@ func interpretText(v string) (string, error) {
 }
 
 // interpret   attributes templates.
+//
+// A value that is a single {expr} is used as is, values mixing text and
+// expressions like btn btn-{props.type} {props.extra} are compiled to a string
+// concatenation of the segments.
 func interpret(v interface{}) (string, error) {
 	switch e := v.(type) {
 	case nil:
 		return "nil", nil
 	case string:
 		e = strings.TrimSpace(e)
-		if strings.HasPrefix(e, "{") {
-			parts, err := expr.ExtractExpressions(e, '{', '}')
+		if strings.Contains(e, "{") {
+			parts, err := expr.ExtractSegments(e, '{', '}')
 			if err != nil {
 				return "", err
 			}
-			var args []ast.Expr
-			for _, v := range parts {
-				if v.Plain && strings.Contains(v.Text, "\"") {
-					a, err := v.QuoteExpr()
-					if err != nil {
-						return "", err
-					}
-					args = append(args, a)
-				} else {
-					a, err := v.Expr()
-					if err != nil {
-						return "", err
-					}
-					args = append(args, a)
+			var x ast.Expr
+			if len(parts) == 1 && !parts[0].Plain {
+				x, err = parts[0].Expr()
+				if err != nil {
+					return "", err
 				}
-			}
-			var e ast.Expr
-			if len(args) == 1 {
-				e = args[0]
 			} else {
-				e = expr.Wrap(args...)
+				x, err = concat(parts)
+				if err != nil {
+					return "", err
+				}
 			}
 			var buf bytes.Buffer
-			printer.Fprint(&buf, token.NewFileSet(), e)
+			printer.Fprint(&buf, token.NewFileSet(), x)
 			return buf.String(), nil
 		}
 		return fmt.Sprintf("%q", e), nil
@@ -167,6 +327,35 @@ func interpret(v interface{}) (string, error) {
 	}
 }
 
+// concat returns ast for joining parts with +. Expressions are passed through
+// fmt.Sprint so they can be of any type.
+func concat(parts []expr.Expression) (ast.Expr, error) {
+	var x ast.Expr
+	for _, v := range parts {
+		var a ast.Expr
+		var err error
+		if v.Plain {
+			a, err = v.QuoteExpr()
+		} else {
+			a, err = v.Expr()
+			a = expr.Wrap(a)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if x == nil {
+			x = a
+		} else {
+			x = &ast.BinaryExpr{
+				X:  x,
+				Op: token.ADD,
+				Y:  a,
+			}
+		}
+	}
+	return x, nil
+}
+
 type GeneratorContext struct {
 	StructName string
 	Recv       string
@@ -175,49 +364,87 @@ type GeneratorContext struct {
 
 // Generate writes a g file that contains generated Render methods for struct
 // defined in the GeneratorContext.
+//
+// Only packages used by the generated code are imported, greact is imported
+// with the name vected which is what generated code refers to.
 func Generate(w io.Writer, pkg string, ctx ...GeneratorContext) error {
-	file := &ast.File{
-		Name: &ast.Ident{
-			Name: pkg,
-		},
-		Decls: []ast.Decl{
-			importDecl(
-				importSpec("context"),
-			),
-			importDecl(
-				importSpec("fmt"),
-			),
-			importDecl(
-				importSpec("github.com/gernest/greact"),
-			),
-			declareAlias(newNode, "vected", "NewNode"),
-			declareAlias(newAttr, "vected", "Attr"),
-			declareAlias(newAttrs, "vected", "Attrs"),
-		},
-	}
+	var funcs []ast.Decl
 	for _, v := range ctx {
 		e, err := render("Render", v.Recv, v.StructName, v.Node)
 		if err != nil {
 			return err
 		}
-		file.Decls = append(file.Decls, e)
+		funcs = append(funcs, e)
+	}
+	used := usedPackages(funcs)
+	imports := importDecl()
+	for _, v := range []struct {
+		name, path string
+	}{
+		{"context", "context"},
+		{"fmt", "fmt"},
+		{"vected", "github.com/gernest/greact"},
+	} {
+		if used[v.name] {
+			imports.Specs = append(imports.Specs, importSpec(v.name, v.path))
+		}
 	}
+	file := &ast.File{
+		Name: &ast.Ident{
+			Name: pkg,
+		},
+	}
+	if len(imports.Specs) > 0 {
+		file.Decls = append(file.Decls, imports)
+	}
+	file.Decls = append(file.Decls,
+		declareAlias(newNode, "vected", "NewNode"),
+		declareAlias(newAttr, "vected", "Attr"),
+		declareAlias(newAttrs, "vected", "Attrs"),
+	)
+	file.Decls = append(file.Decls, funcs...)
 	return format.Node(w, token.NewFileSet(), file)
 }
 
-func importSpec(pkg string) *ast.ImportSpec {
-	return &ast.ImportSpec{
+// usedPackages returns names of packages referenced by selectors in decls. The
+// node aliases always refer to vected.
+func usedPackages(decls []ast.Decl) map[string]bool {
+	used := map[string]bool{"vected": true}
+	for _, d := range decls {
+		ast.Inspect(d, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// importSpec returns an import of path, name is only set when it differs from
+// the last element of path.
+func importSpec(name, path string) *ast.ImportSpec {
+	spec := &ast.ImportSpec{
 		Path: &ast.BasicLit{
 			Kind:  token.STRING,
-			Value: fmt.Sprintf("%q", pkg),
+			Value: fmt.Sprintf("%q", path),
 		},
 	}
+	if name != path[strings.LastIndex(path, "/")+1:] {
+		spec.Name = &ast.Ident{
+			Name: name,
+		}
+	}
+	return spec
 }
 
-func importDecl(pkg *ast.ImportSpec) *ast.GenDecl {
+func importDecl(pkg ...ast.Spec) *ast.GenDecl {
 	return &ast.GenDecl{
-		Tok:   token.IMPORT,
-		Specs: []ast.Spec{pkg},
+		Tok:    token.IMPORT,
+		Lparen: 1,
+		Specs:  pkg,
 	}
 }
 
@@ -414,6 +641,24 @@ func h(node *Node) (*ast.CallExpr, error) {
 	}
 	var attrs []ast.Expr
 	for _, v := range node.Attr {
+		if v.Key == spreadKey {
+			e, err := parser.ParseExpr(v.Val.(string))
+			if err != nil {
+				return nil, err
+			}
+			attrs = append(attrs, &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X: &ast.Ident{
+						Name: "vected",
+					},
+					Sel: &ast.Ident{
+						Name: "Spread",
+					},
+				},
+				Args: []ast.Expr{e},
+			})
+			continue
+		}
 		txt, err := interpret(v.Val)
 		if err != nil {
 			return nil, err
@@ -2,6 +2,7 @@ package greact
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +43,12 @@ func TestInterpret(t *testing.T) {
 	}{
 		{`{"hello"}`, `"hello"`},
 		{"{props.class}", "props.class"},
+		{
+			`btn btn-{props.String("type")} {props.String("extra")}`,
+			`"btn btn-" + fmt.Sprint(props.String("type")) + " " + fmt.Sprint(props.String("extra"))`,
+		},
+		{"{a}{b}", "fmt.Sprint(a) + fmt.Sprint(b)"},
+		{"btn", `"btn"`},
 	}
 	for _, v := range sample {
 		got, err := interpret(v.expr)
@@ -126,3 +133,142 @@ func TestGenerate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGenerate_interpolation(t *testing.T) {
+	n, err := ParseString(`<button class='btn btn-{props.String("type")} {props.String("extra")}'></button>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = Generate(&out, "hello", GeneratorContext{
+		StructName: "Button",
+		Recv:       "b",
+		Node:       n,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `package hello
+
+import (
+	"context"
+	"fmt"
+	vected "github.com/gernest/greact"
+)
+
+var vH = vected.NewNode
+var vHA = vected.Attr
+var vHAT = vected.Attrs
+
+func (b *Button) Render(ctx context.Context, props vected.Props, state vected.State) *vected.Node {
+	return vH(3, "", "button", vHAT(vHA("", "class", "btn btn-"+fmt.Sprint(props.String("type"))+" "+fmt.Sprint(props.String("extra")))))
+}
+`
+	if out.String() != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, out.String())
+	}
+}
+
+func TestGenerate_spread(t *testing.T) {
+	sample := []struct {
+		src, render string
+	}{
+		{
+			src:    `<div id="main" {...props["rest"]}><p {... props["Inner"] }>hello</p></div>`,
+			render: `vH(3, "", "div", vHAT(vHA("", "id", "main"), vected.Spread(props["rest"])), vH(3, "", "p", vHAT(vected.Spread(props["Inner"])), vH(1, "", fmt.Sprint("hello"), nil)))`,
+		},
+		{
+			src:    `<div {...vected.Props{"title": "}"}}>hello</div>`,
+			render: `vH(3, "", "div", vHAT(vected.Spread(vected.Props{"title": "}"})), vH(1, "", fmt.Sprint("hello"), nil))`,
+		},
+	}
+	for _, v := range sample {
+		n, err := ParseString(v.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		err = Generate(&out, "hello", GeneratorContext{
+			StructName: "Box",
+			Recv:       "b",
+			Node:       n,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := `package hello
+
+import (
+	"context"
+	"fmt"
+	vected "github.com/gernest/greact"
+)
+
+var vH = vected.NewNode
+var vHA = vected.Attr
+var vHAT = vected.Attrs
+
+func (b *Box) Render(ctx context.Context, props vected.Props, state vected.State) *vected.Node {
+	return ` + v.render + `
+}
+`
+		if out.String() != expect {
+			t.Errorf("%s: expected\n%s\ngot\n%s", v.src, expect, out.String())
+		}
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	sample := []struct {
+		src, err string
+	}{
+		{"<div><p>hello</p><br><img/></div>", ""},
+		{"<div>\n\t<p>hello</div>", "2:10: unexpected </div>, <p> opened at 2:2 is not closed"},
+		{"<div>\n<span>hello</span>", "1:1: unclosed <div>"},
+		{"<div></div></p>", "1:12: unexpected </p>"},
+	}
+	for _, v := range sample {
+		_, err := ParseStrict(strings.NewReader(v.src))
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != v.err {
+			t.Errorf("%q: expected error %q got %q", v.src, v.err, got)
+		}
+	}
+}
+
+func TestGenerate_imports(t *testing.T) {
+	n, err := ParseString(`<div class={props.String("class")}></div>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = Generate(&out, "hello", GeneratorContext{
+		StructName: "Box",
+		Recv:       "b",
+		Node:       n,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `package hello
+
+import (
+	"context"
+	vected "github.com/gernest/greact"
+)
+
+var vH = vected.NewNode
+var vHA = vected.Attr
+var vHAT = vected.Attrs
+
+func (b *Box) Render(ctx context.Context, props vected.Props, state vected.State) *vected.Node {
+	return vH(3, "", "div", vHAT(vHA("", "class", props.String("class"))))
+}
+`
+	if out.String() != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, out.String())
+	}
+}
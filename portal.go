@@ -0,0 +1,113 @@
+package greact
+
+import "context"
+
+// portalTarget is the key of the attribute holding the container of a portal.
+const portalTarget = "target"
+
+// Portal returns a node that renders child into target instead of the parent
+// it is rendered in. Use it for modals and tooltips that must escape the
+// overflow and stacking of their parent.
+//
+// 	return NewNode(ElementNode, "", "div", nil,
+// 		button,
+// 		greact.Portal(body, modal),
+// 	)
+//
+// An empty comment keeps the place of the portal in the parent. Components in
+// child receive the context of the portal's owner, and child is removed from
+// target when the portal is unmounted. On the server a portal renders as the
+// empty comment only.
+func Portal(target Element, child *Node) *Node {
+	return &Node{
+		Type:     PortalNode,
+		Attr:     []Attribute{{Key: portalTarget, Val: target}},
+		Children: []*Node{child},
+	}
+}
+
+// portal is the content of a rendered portal.
+type portal struct {
+	target Element
+
+	// root is the element child was rendered to, it is nil until the first
+	// render.
+	root Element
+}
+
+// portalOf returns the portal whose place is kept by elem, or nil if elem isn't
+// the comment of a portal.
+func (v *Vected) portalOf(elem Element) *portal {
+	if !Valid(elem) || !isComment(elem) {
+		return nil
+	}
+	id := elem.Get(AttrKey)
+	if id.Type() != TypeNumber {
+		return nil
+	}
+	return v.portals[id.Int()]
+}
+
+// diffPortal renders the portal node in place of elem and returns the comment
+// keeping its place.
+func (v *Vected) diffPortal(ctx context.Context, elem Element, node *Node, mountAll bool) Element {
+	var target Element
+	var child *Node
+	for _, a := range node.Attr {
+		if a.Key == portalTarget {
+			target, _ = a.Val.(Element)
+		}
+	}
+	if len(node.Children) > 0 {
+		child = node.Children[0]
+	}
+	out := elem
+	p := v.portalOf(elem)
+	if p == nil {
+		out = v.Document.Call("createComment", "")
+		v.traceNew(node, out, elem)
+		if Valid(elem) {
+			if parent := elem.Get("parentNode"); Valid(parent) {
+				parent.Call("replaceChild", out, elem)
+			}
+			v.recollectNodeTree(elem, true)
+		}
+		id := idPool.Get().(int)
+		out.Set(AttrKey, id)
+		p = &portal{}
+		v.portals[id] = p
+	} else {
+		v.trace(TraceReuse, node, out)
+		if Valid(p.root) && !IsEqual(p.target, target) {
+			// the content moves to the new container.
+			v.recollectNodeTree(p.root, false)
+			p.root = nil
+		}
+	}
+	p.target = target
+	if !Valid(target) {
+		return out
+	}
+	root := v.idiff(ctx, p.root, child, mountAll, false)
+	if Valid(p.root) && !IsEqual(root, p.root) && Valid(p.root.Get("parentNode")) {
+		target.Call("replaceChild", root, p.root)
+		v.recollectNodeTree(p.root, false)
+	} else if parent := root.Get("parentNode"); !Valid(parent) || !IsEqual(parent, target) {
+		target.Call("appendChild", root)
+	}
+	p.root = root
+	return out
+}
+
+// releasePortal removes the content of the portal whose place is kept by elem
+// from its container.
+func (v *Vected) releasePortal(elem Element) {
+	p := v.portalOf(elem)
+	if p == nil {
+		return
+	}
+	delete(v.portals, elem.Get(AttrKey).Int())
+	if Valid(p.root) {
+		v.recollectNodeTree(p.root, false)
+	}
+}
@@ -0,0 +1,88 @@
+package greact
+
+import (
+	"context"
+	"testing"
+)
+
+// portalTheme is the context key of the theme provided by portalOwner.
+type portalTheme struct{}
+
+// portalOwner renders its node prop with a dark theme in the context.
+type portalOwner struct {
+	Core
+}
+
+func (p *portalOwner) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, portalTheme{}, "dark")
+}
+
+func (p *portalOwner) Render(ctx context.Context, props Props, state State) *Node {
+	return props["node"].(*Node)
+}
+
+// portalThemed renders the theme found in its context.
+type portalThemed struct {
+	Core
+}
+
+func (p *portalThemed) Render(ctx context.Context, props Props, state State) *Node {
+	theme, _ := ctx.Value(portalTheme{}).(string)
+	return NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", theme, nil))
+}
+
+func TestPortal(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("owner", &portalOwner{})
+	v.Register("themed", &portalThemed{})
+	v.Register("spy", &unmountSpy{})
+	h := NewNode
+	body := newObject()
+	node := func(open bool, text string) *Node {
+		modal := Empty()
+		if open {
+			modal = Portal(body, h(ElementNode, "", "div", nil,
+				h(ElementNode, "", "themed", nil),
+				h(ElementNode, "", "spy", nil),
+				h(ElementNode, "", "p", nil, h(TextNode, "", text, nil)),
+			))
+		}
+		return h(ElementNode, "", "owner", Attrs(Attr("", "node", h(ElementNode, "", "section", nil,
+			h(ElementNode, "", "button", nil, h(TextNode, "", "open", nil)),
+			modal,
+		))))
+	}
+	el := newObject()
+	section := v.Render(node(true, "hello"), el).(*object)
+	if n := len(section.children); n != 2 || !isComment(section.children[1]) {
+		t.Fatalf("expected the portal to keep its place with a comment got %d children", n)
+	}
+	if len(body.children) != 1 {
+		t.Fatalf("expected the content to be rendered into the target got %d children", len(body.children))
+	}
+	content := body.children[0]
+	if got := content.children[0].children[0].nodeValue; got != "dark" {
+		t.Errorf("expected components in the portal to receive the owner's context got %q", got)
+	}
+	spy := v.findComponent(content.children[1]).(*unmountSpy)
+
+	v.Render(node(true, "world"), el, section)
+	if len(body.children) != 1 || body.children[0] != content {
+		t.Fatal("expected the content to be updated in place")
+	}
+	if got := content.children[2].children[0].Get("nodeValue").String(); got != "world" {
+		t.Errorf("expected world got %q", got)
+	}
+
+	v.Render(node(false, ""), el, section)
+	if len(body.children) != 0 {
+		t.Errorf("expected the content to be removed from the target got %d children", len(body.children))
+	}
+	if !spy.unmounted {
+		t.Error("expected components in the portal to be unmounted")
+	}
+	if len(v.portals) != 0 {
+		t.Errorf("expected the portal to be forgotten got %d", len(v.portals))
+	}
+}
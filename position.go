@@ -0,0 +1,142 @@
+package greact
+
+// Rect is a rectangle in viewport coordinates, like the DOMRect returned by
+// getBoundingClientRect.
+type Rect struct {
+	Left, Top, Width, Height float64
+}
+
+// Right returns the x coordinate of the right edge of r.
+func (r Rect) Right() float64 { return r.Left + r.Width }
+
+// Bottom returns the y coordinate of the bottom edge of r.
+func (r Rect) Bottom() float64 { return r.Top + r.Height }
+
+// BoundingRect returns the result of calling getBoundingClientRect on elem.
+func BoundingRect(elem Element) Rect {
+	v := elem.Call("getBoundingClientRect")
+	if !Valid(v) {
+		return Rect{}
+	}
+	num := func(k string) float64 {
+		if n := v.Get(k); n.Type() == TypeNumber {
+			return n.Float()
+		}
+		return 0
+	}
+	return Rect{
+		Left:   num("left"),
+		Top:    num("top"),
+		Width:  num("width"),
+		Height: num("height"),
+	}
+}
+
+// Placement is the side of the anchor a floating element is placed on.
+type Placement uint
+
+// supported placements
+const (
+	PlaceBottom Placement = iota
+	PlaceTop
+	PlaceRight
+	PlaceLeft
+)
+
+func (p Placement) String() string {
+	switch p {
+	case PlaceBottom:
+		return "bottom"
+	case PlaceTop:
+		return "top"
+	case PlaceRight:
+		return "right"
+	case PlaceLeft:
+		return "left"
+	default:
+		return "unknown"
+	}
+}
+
+// opposite returns the placement on the other side of the anchor.
+func (p Placement) opposite() Placement {
+	switch p {
+	case PlaceTop:
+		return PlaceBottom
+	case PlaceRight:
+		return PlaceLeft
+	case PlaceLeft:
+		return PlaceRight
+	default:
+		return PlaceTop
+	}
+}
+
+// Position computes where to put target, a tooltip or popover, next to anchor.
+// The returned top and left are viewport coordinates for a target with
+// position: fixed, add the scroll offsets for position: absolute.
+//
+// target is centered on the anchor along the side given by placement. When it
+// would overflow viewport it is flipped to the opposite side, if that side has
+// room, and the returned placement tells which side was used. target is then
+// shifted to stay within the viewport along the other axis.
+func Position(anchor, target Element, placement Placement, viewport Rect) (top, left float64, p Placement) {
+	a := BoundingRect(anchor)
+	t := BoundingRect(target)
+	r := place(a, t, placement)
+	p = placement
+	if overflows(r, viewport, p) {
+		if o := place(a, t, p.opposite()); !overflows(o, viewport, p) {
+			r, p = o, placement.opposite()
+		}
+	}
+	switch p {
+	case PlaceTop, PlaceBottom:
+		r.Left = clamp(r.Left, viewport.Left, viewport.Right()-r.Width)
+	default:
+		r.Top = clamp(r.Top, viewport.Top, viewport.Bottom()-r.Height)
+	}
+	return r.Top, r.Left, p
+}
+
+// place returns the rect of target placed on the side p of anchor.
+func place(anchor, target Rect, p Placement) Rect {
+	r := target
+	switch p {
+	case PlaceTop, PlaceBottom:
+		r.Left = anchor.Left + (anchor.Width-target.Width)/2
+		if p == PlaceTop {
+			r.Top = anchor.Top - target.Height
+		} else {
+			r.Top = anchor.Bottom()
+		}
+	default:
+		r.Top = anchor.Top + (anchor.Height-target.Height)/2
+		if p == PlaceLeft {
+			r.Left = anchor.Left - target.Width
+		} else {
+			r.Left = anchor.Right()
+		}
+	}
+	return r
+}
+
+// overflows returns true if r sticks out of viewport along the axis of p.
+func overflows(r, viewport Rect, p Placement) bool {
+	switch p {
+	case PlaceTop, PlaceBottom:
+		return r.Top < viewport.Top || r.Bottom() > viewport.Bottom()
+	default:
+		return r.Left < viewport.Left || r.Right() > viewport.Right()
+	}
+}
+
+func clamp(v, min, max float64) float64 {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
@@ -0,0 +1,30 @@
+package greact
+
+import "testing"
+
+func TestPosition(t *testing.T) {
+	viewport := Rect{Width: 800, Height: 600}
+	target := newObject()
+	target.rect = Rect{Width: 100, Height: 40}
+	sample := []struct {
+		name      string
+		anchor    Rect
+		placement Placement
+		top, left float64
+		expect    Placement
+	}{
+		{"bottom", Rect{Left: 100, Top: 100, Width: 50, Height: 20}, PlaceBottom, 120, 75, PlaceBottom},
+		{"bottom flips to top", Rect{Left: 100, Top: 570, Width: 50, Height: 20}, PlaceBottom, 530, 75, PlaceTop},
+		{"top flips to bottom", Rect{Left: 100, Top: 10, Width: 50, Height: 20}, PlaceTop, 30, 75, PlaceBottom},
+		{"right flips to left", Rect{Left: 750, Top: 100, Width: 50, Height: 20}, PlaceRight, 90, 650, PlaceLeft},
+		{"clamped to the viewport", Rect{Left: 0, Top: 100, Width: 20, Height: 20}, PlaceBottom, 120, 0, PlaceBottom},
+	}
+	for _, s := range sample {
+		anchor := newObject()
+		anchor.rect = s.anchor
+		top, left, p := Position(anchor, target, s.placement, viewport)
+		if top != s.top || left != s.left || p != s.expect {
+			t.Errorf("%s: expected %v,%v %v got %v,%v %v", s.name, s.top, s.left, s.expect, top, left, p)
+		}
+	}
+}
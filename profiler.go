@@ -0,0 +1,93 @@
+package greact
+
+import (
+	"context"
+	"time"
+)
+
+// supported profiler phases.
+const (
+	PhaseMount  = "mount"
+	PhaseUpdate = "update"
+)
+
+// ProfilerInfo is the measurement reported by a Profiler after its subtree has
+// been committed to the dom.
+type ProfilerInfo struct {
+	// ID is the value of the id prop of the Profiler.
+	ID string
+
+	// Phase is either PhaseMount or PhaseUpdate.
+	Phase string
+
+	// ActualDuration is the time spent rendering the subtree for this commit.
+	ActualDuration time.Duration
+
+	// BaseDuration is the time it took to render the whole subtree when it was
+	// first mounted. Comparing it with ActualDuration shows how much work the
+	// update saved.
+	BaseDuration time.Duration
+}
+
+// Profiler is a component that measures how long it takes to render its
+// children. Its onRender prop of type func(ProfilerInfo) is called after every
+// mount and update.
+//
+// 	<profiler id="sidebar" onRender={fn}>
+// 		<sidebar />
+// 	</profiler>
+//
+// Timing starts in the will mount/update hooks and stops in the did
+// mount/update hooks, so the measurement covers rendering and diffing of the
+// whole subtree.
+type Profiler struct {
+	Core
+	phase string
+	start time.Time
+	base  time.Duration
+}
+
+// ComponentWillMount starts measuring the mount phase.
+func (p *Profiler) ComponentWillMount() {
+	p.phase = PhaseMount
+	p.start = time.Now()
+}
+
+// ComponentDidMount reports the mount measurement.
+func (p *Profiler) ComponentDidMount() {
+	p.base = time.Since(p.start)
+	p.report(p.base)
+}
+
+// ComponentWillUpdate starts measuring the update phase.
+func (p *Profiler) ComponentWillUpdate(context.Context, Props, State) Props {
+	p.phase = PhaseUpdate
+	p.start = time.Now()
+	return nil
+}
+
+// ComponentDidUpdate reports the update measurement.
+func (p *Profiler) ComponentDidUpdate(Props, State) {
+	p.report(time.Since(p.start))
+}
+
+func (p *Profiler) report(actual time.Duration) {
+	if fn, ok := p.Props()["onRender"].(func(ProfilerInfo)); ok {
+		fn(ProfilerInfo{
+			ID:             p.Props().String("id"),
+			Phase:          p.phase,
+			ActualDuration: actual,
+			BaseDuration:   p.base,
+		})
+	}
+}
+
+// Render renders the children of the profiler. Multiple children are wrapped
+// in a div.
+func (p *Profiler) Render(ctx context.Context, props Props, state State) *Node {
+	children := props.Children()
+	if len(children) == 1 {
+		return children[0]
+	}
+	return NewNode(ElementNode, "", "div", nil, children...)
+}
@@ -0,0 +1,42 @@
+package greact
+
+import "testing"
+
+func TestProfiler(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("profiler", &Profiler{})
+	var info []ProfilerInfo
+	onRender := func(i ProfilerInfo) {
+		info = append(info, i)
+	}
+	node := func() *Node {
+		return NewNode(ElementNode, "", "profiler", Attrs(
+			Attr("", "id", "app"),
+			Attr("", "onRender", onRender),
+		), NewNode(ElementNode, "", "div", nil,
+			NewNode(TextNode, "", "hello", nil),
+		))
+	}
+	el := newObject()
+	base := v.Render(node(), el)
+	if len(info) != 1 {
+		t.Fatalf("expected 1 measurement got %d", len(info))
+	}
+	if info[0].Phase != PhaseMount {
+		t.Errorf("expected %s got %s", PhaseMount, info[0].Phase)
+	}
+	if info[0].ID != "app" {
+		t.Errorf("expected app got %s", info[0].ID)
+	}
+	v.Render(node(), el, base)
+	if len(info) != 2 {
+		t.Fatalf("expected 2 measurements got %d", len(info))
+	}
+	if info[1].Phase != PhaseUpdate {
+		t.Errorf("expected %s got %s", PhaseUpdate, info[1].Phase)
+	}
+	if info[1].BaseDuration != info[0].ActualDuration {
+		t.Error("expected base duration to be the mount duration")
+	}
+}
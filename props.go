@@ -2,7 +2,12 @@ package greact
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Props is a map of properties. These are used to pass values to components.
@@ -20,6 +25,41 @@ func MergeProps(a, b Props) Props {
 	return m
 }
 
+// PropsBuilder builds Props with chained calls.
+//
+// 	props := NewProps().
+// 		Set("name", "gopher").
+// 		SetIf(selected, "class", "active").
+// 		Build()
+type PropsBuilder struct {
+	props Props
+}
+
+// NewProps returns a builder for Props.
+func NewProps() *PropsBuilder {
+	return &PropsBuilder{props: make(Props)}
+}
+
+// Set sets key to value.
+func (b *PropsBuilder) Set(key string, value interface{}) *PropsBuilder {
+	b.props[key] = value
+	return b
+}
+
+// SetIf sets key to value only when cond is true.
+func (b *PropsBuilder) SetIf(cond bool, key string, value interface{}) *PropsBuilder {
+	if cond {
+		b.props[key] = value
+	}
+	return b
+}
+
+// Build returns the built Props. The builder can be reused, later calls don't
+// change Props that were already returned.
+func (b *PropsBuilder) Build() Props {
+	return MergeProps(b.props, nil)
+}
+
 // Children returns child components stored in props.
 func (p Props) Children() []*Node {
 	if c, ok := p["children"]; ok {
@@ -75,6 +115,78 @@ func Verify(key string, typ PropType, props Props) error {
 	return nil
 }
 
+// maxDumpValue is the maximum number of runes of a value printed by
+// Props.Format.
+const maxDumpValue = 32
+
+// Format implements fmt.Formatter, the %s and %v verbs print p with sorted
+// keys and long values truncated. This gives stable output when debugging how
+// props flow between components. Other verbs, and %#v, print p like a map.
+//
+// 	fmt.Println(Props{"name": "gopher", "age": 10})
+// 	// Props{age: 10, name: "gopher"}
+func (p Props) Format(f fmt.State, verb rune) {
+	if (verb != 's' && verb != 'v') || f.Flag('#') {
+		m := fmt.Sprintf(directive(f, verb), map[string]interface{}(p))
+		if verb == 'v' {
+			m = "greact.Props" + strings.TrimPrefix(m, "map[string]interface {}")
+		}
+		io.WriteString(f, m)
+		return
+	}
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	io.WriteString(f, "Props{")
+	for i, k := range keys {
+		if i > 0 {
+			io.WriteString(f, ", ")
+		}
+		var v string
+		switch e := p[k].(type) {
+		case string:
+			v = fmt.Sprintf("%q", e)
+		default:
+			if reflect.TypeOf(e) != nil && reflect.TypeOf(e).Kind() == reflect.Func {
+				v = "func"
+			} else {
+				v = fmt.Sprint(e)
+			}
+		}
+		if utf8.RuneCountInString(v) > maxDumpValue {
+			n := 0
+			for i := 0; i < maxDumpValue; i++ {
+				_, size := utf8.DecodeRuneInString(v[n:])
+				n += size
+			}
+			v = v[:n] + "..."
+		}
+		fmt.Fprintf(f, "%s: %s", k, v)
+	}
+	io.WriteString(f, "}")
+}
+
+// directive returns the formatting directive, like %-8d, that f and verb came
+// from.
+func directive(f fmt.State, verb rune) string {
+	b := []byte{'%'}
+	for _, c := range "+-# 0" {
+		if f.Flag(int(c)) {
+			b = append(b, byte(c))
+		}
+	}
+	if w, ok := f.Width(); ok {
+		b = strconv.AppendInt(b, int64(w), 10)
+	}
+	if p, ok := f.Precision(); ok {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(p), 10)
+	}
+	return string(append(b, string(verb)...))
+}
+
 func (p Props) String(key string) string {
 	return getString(p, key)
 }
@@ -0,0 +1,61 @@
+package greact
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProps_Format(t *testing.T) {
+	props := Props{
+		"name":    "gopher",
+		"age":     10,
+		"active":  true,
+		"tags":    []string{"a", "b"},
+		"onClick": func() {},
+		"bio":     strings.Repeat("x", 40),
+		"nothing": nil,
+	}
+	expect := `Props{active: true, age: 10, bio: "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx..., name: "gopher", nothing: <nil>, onClick: func, tags: [a b]}`
+	for i := 0; i < 10; i++ {
+		if got := fmt.Sprint(props); got != expect {
+			t.Fatalf("expected %s got %s", expect, got)
+		}
+	}
+	long := Props{"name": strings.Repeat("é", 40)}
+	expect = `Props{name: "` + strings.Repeat("é", 31) + `...}`
+	if got := fmt.Sprintf("%s", long); got != expect {
+		t.Errorf("expected %s got %s", expect, got)
+	}
+	short := Props{"age": 10}
+	for _, v := range []struct {
+		format, expect string
+	}{
+		{"%v", "Props{age: 10}"},
+		{"%#v", `greact.Props{"age":10}`},
+		{"%d", "map[%!d(string=age):10]"},
+		{"%x", "map[616765:a]"},
+	} {
+		if got := fmt.Sprintf(v.format, short); got != v.expect {
+			t.Errorf("%s: expected %s got %s", v.format, v.expect, got)
+		}
+	}
+}
+
+func TestPropsBuilder(t *testing.T) {
+	b := NewProps().
+		Set("a", 1).
+		SetIf(false, "b", 2).
+		SetIf(true, "c", 3)
+	props := b.Build()
+	if _, ok := props["b"]; ok {
+		t.Error("expected b to be skipped")
+	}
+	if props["a"] != 1 || props["c"] != 3 {
+		t.Errorf("expected a and c to be set got %v", props)
+	}
+	b.Set("d", 4)
+	if _, ok := props["d"]; ok {
+		t.Error("expected built props not to change")
+	}
+}
@@ -0,0 +1,405 @@
+package greact
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServerAttr is the attribute marking elements rendered by RenderToString, its
+// value is a checksum of the element's name, attributes and text.
+//
+// It is an attribute and not the AttrKey property set on elements rendered in
+// the browser, so diff finds no attribute cache on the server markup and
+// hydrates it. While hydrating every marked element is checked against the
+// checksum of its virtual node, an element that doesn't match is logged and
+// rendered again from scratch together with its children, instead of patching
+// markup that may belong to a different tree. The marker is removed by the
+// first render on the client.
+const ServerAttr = "data-" + AttrKey
+
+// RenderToString renders node to html without a dom, for instance to serve
+// the first paint of a page and hydrate it in the browser.
+//
+// Components are created and rendered with ctx wrapped in ServerContext, they
+// receive ComponentWillMount but none of the callbacks run after mounting.
+// Event handlers, refs and keys are dropped. A panic in a component is
+// returned as an error.
+func (v *Vected) RenderToString(ctx context.Context, node *Node) (string, error) {
+	s := &htmlSerializer{}
+	if err := v.walk(ServerContext(ctx), s, fragmentRoot(node), ""); err != nil {
+		return "", err
+	}
+	return s.buf.String(), nil
+}
+
+// RenderToText renders node to readable plain text, for instance for the text
+// part of an email. Components are rendered like with RenderToString.
+//
+// Only the text is kept. Block elements like p and div start on a new line,
+// list items are prefixed with a bullet and items of ordered lists with their
+// number. Attributes, comments, scripts and styles are dropped.
+func (v *Vected) RenderToText(ctx context.Context, node *Node) (string, error) {
+	s := &textSerializer{}
+	if err := v.walk(ServerContext(ctx), s, fragmentRoot(node), ""); err != nil {
+		return "", err
+	}
+	return strings.Trim(s.buf.String(), "\n"), nil
+}
+
+// serializer writes the nodes visited by walk, components are already rendered
+// when it sees them.
+type serializer interface {
+	// text writes the text data of a child of the element named parent.
+	text(parent, data string)
+	comment(data string)
+	portal()
+
+	// open starts the element node and returns false if its children must not
+	// be walked.
+	open(node *Node) bool
+
+	// close ends the element node, it is called even if open returned false.
+	close(node *Node)
+}
+
+// walk renders the components in node and passes the result to s, parent is
+// the name of the element node is a child of.
+func (v *Vected) walk(ctx context.Context, s serializer, node *Node, parent string) error {
+	if node == nil {
+		node = Empty()
+	}
+	switch node.Type {
+	case TextNode:
+		s.text(parent, node.Data)
+	case CommentNode:
+		s.comment(node.Data)
+	case PortalNode:
+		// the content belongs to another container, only the place is kept.
+		s.portal()
+	case FragmentNode:
+		for _, ch := range flatten(node.Children) {
+			if err := v.walk(ctx, s, ch, parent); err != nil {
+				return err
+			}
+		}
+	case ElementNode:
+		if r, ok := v.components[node.Data]; ok {
+			props := getNodeProps(node)
+			rendered, childCtx, err := renderStatic(ctx, r.instance(props), props)
+			if err != nil {
+				return err
+			}
+			return v.walk(childCtx, s, fragmentRoot(rendered), parent)
+		}
+		if s.open(node) {
+			for _, ch := range node.Children {
+				if err := v.walk(ctx, s, ch, node.Data); err != nil {
+					return err
+				}
+			}
+		}
+		s.close(node)
+	}
+	return nil
+}
+
+// htmlSerializer writes the html rendered by RenderToString.
+type htmlSerializer struct {
+	buf bytes.Buffer
+}
+
+func (s *htmlSerializer) text(parent, data string) {
+	s.buf.WriteString(escapeText(parent, data))
+}
+
+func (s *htmlSerializer) comment(data string) {
+	s.buf.WriteString("<!--")
+	s.buf.WriteString(escapeComment(data))
+	s.buf.WriteString("-->")
+}
+
+func (s *htmlSerializer) portal() {
+	s.buf.WriteString("<!---->")
+}
+
+func (s *htmlSerializer) open(node *Node) bool {
+	buf := &s.buf
+	buf.WriteByte('<')
+	buf.WriteString(node.Data)
+	var inner interface{}
+	for _, a := range node.Attr {
+		if a.Key == "dangerouslySetInnerHTML" {
+			inner = a.Val
+			continue
+		}
+		writeAttr(buf, a)
+	}
+	buf.WriteString(" " + ServerAttr + `="` + checksum(node) + `"`)
+	if voidElements[node.Data] {
+		buf.WriteString("/>")
+		return false
+	}
+	buf.WriteByte('>')
+	if inner != nil {
+		buf.WriteString(fmt.Sprint(inner))
+		return false
+	}
+	return true
+}
+
+func (s *htmlSerializer) close(node *Node) {
+	if voidElements[node.Data] {
+		return
+	}
+	s.buf.WriteString("</")
+	s.buf.WriteString(node.Data)
+	s.buf.WriteByte('>')
+}
+
+// blockElements are the elements RenderToText puts on their own line.
+var blockElements = map[string]bool{
+	"address":    true,
+	"article":    true,
+	"aside":      true,
+	"blockquote": true,
+	"dd":         true,
+	"div":        true,
+	"dl":         true,
+	"dt":         true,
+	"fieldset":   true,
+	"figcaption": true,
+	"figure":     true,
+	"footer":     true,
+	"form":       true,
+	"h1":         true,
+	"h2":         true,
+	"h3":         true,
+	"h4":         true,
+	"h5":         true,
+	"h6":         true,
+	"header":     true,
+	"hr":         true,
+	"li":         true,
+	"main":       true,
+	"nav":        true,
+	"ol":         true,
+	"p":          true,
+	"pre":        true,
+	"section":    true,
+	"table":      true,
+	"tr":         true,
+	"ul":         true,
+}
+
+// textSerializer writes the plain text rendered by RenderToText.
+type textSerializer struct {
+	buf bytes.Buffer
+
+	// lists holds the number of the last item of each open list, it is -1 for
+	// unordered lists.
+	lists []int
+}
+
+func (s *textSerializer) text(parent, data string) {
+	s.buf.WriteString(data)
+}
+
+func (s *textSerializer) comment(string) {}
+
+func (s *textSerializer) portal() {}
+
+// newline ends the current line unless it is empty.
+func (s *textSerializer) newline() {
+	if b := s.buf.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		s.buf.WriteByte('\n')
+	}
+}
+
+func (s *textSerializer) open(node *Node) bool {
+	switch node.Data {
+	case "br":
+		s.buf.WriteByte('\n')
+	case "ul":
+		s.lists = append(s.lists, -1)
+	case "ol":
+		s.lists = append(s.lists, 0)
+	}
+	if blockElements[node.Data] {
+		s.newline()
+	}
+	if node.Data == "li" && len(s.lists) > 0 {
+		i := len(s.lists) - 1
+		s.buf.WriteString(strings.Repeat("  ", i))
+		if s.lists[i] < 0 {
+			s.buf.WriteString("- ")
+		} else {
+			s.lists[i]++
+			s.buf.WriteString(strconv.Itoa(s.lists[i]) + ". ")
+		}
+	}
+	return !rawTextElements[node.Data]
+}
+
+func (s *textSerializer) close(node *Node) {
+	switch node.Data {
+	case "ul", "ol":
+		if len(s.lists) > 0 {
+			s.lists = s.lists[:len(s.lists)-1]
+		}
+	}
+	if blockElements[node.Data] {
+		s.newline()
+	}
+}
+
+// writeAttr writes a as an html attribute the way setAccessor would apply it
+// to an element, attributes that only exist in the browser are skipped.
+func writeAttr(buf *bytes.Buffer, a Attribute) {
+	name := a.Key
+	if name == "className" {
+		name = "class"
+	}
+	var value string
+	boolean := false
+	switch e := a.Val.(type) {
+	case nil:
+		return
+	case string:
+		value = e
+	case bool:
+		switch {
+		case isStringBool(name):
+			value = fmt.Sprint(e)
+		case !e:
+			return
+		default:
+			boolean = true
+		}
+	case map[string]string:
+		if name != "style" {
+			return
+		}
+		keys := make([]string, 0, len(e))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var s []string
+		for _, k := range keys {
+			s = append(s, k+": "+e[k])
+		}
+		value = strings.Join(s, "; ")
+	default:
+		switch reflect.ValueOf(e).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			value = fmt.Sprint(e)
+		default:
+			// functions, refs and complex values only make sense in the browser.
+			return
+		}
+	}
+	switch {
+	case name == "key" || name == "ref" || name == AttrKey:
+		return
+	case strings.HasPrefix(name, "on"):
+		return
+	}
+	buf.WriteByte(' ')
+	if a.Namespace != "" {
+		buf.WriteString(a.Namespace)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(name)
+	if boolean {
+		return
+	}
+	buf.WriteString(`="`)
+	buf.WriteString(html.EscapeString(value))
+	buf.WriteByte('"')
+}
+
+// checksum returns the ServerAttr value of the element node. Only the element
+// itself, its attributes and its text children are hashed, child elements have
+// their own checksum and components aren't rendered yet when the client
+// computes it.
+func checksum(node *Node) string {
+	var buf bytes.Buffer
+	buf.WriteString(node.Data)
+	for _, a := range node.Attr {
+		if a.Key == "dangerouslySetInnerHTML" {
+			fmt.Fprint(&buf, a.Val)
+			continue
+		}
+		writeAttr(&buf, a)
+	}
+	for _, ch := range flatten(node.Children) {
+		if ch != nil && ch.Type == TextNode {
+			buf.WriteByte(0)
+			buf.WriteString(ch.Data)
+		}
+	}
+	h := fnv.New32a()
+	h.Write(buf.Bytes())
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+// hydrationMismatch returns true if elem was rendered by RenderToString from
+// a different node.
+func hydrationMismatch(elem Element, node *Node) bool {
+	sum := elem.Call("getAttribute", ServerAttr)
+	if sum.Type() != TypeString {
+		return false
+	}
+	return sum.String() != checksum(node)
+}
+
+// renderStatic runs the lifecycle methods of the new instance inst that come
+// before mounting and returns what it renders with props together with the
+// context passed to its children.
+func renderStatic(ctx context.Context, inst Component, props Props) (node *Node, childCtx context.Context, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("greact: rendering %s: %v", Name(inst), r)
+		}
+	}()
+	props = MergeProps(nil, props)
+	delete(props, "key")
+	delete(props, "ref")
+	if p, ok := inst.(InitProps); ok {
+		props = MergeProps(p.InitProps(), props)
+	}
+	core := inst.core()
+	core.context = ctx
+	core.props = props
+	if s, ok := inst.(InitState); ok {
+		core.state = s.InitState()
+	}
+	// same order as setProps followed by renderComponent on the client.
+	if _, ok := inst.(DerivedState); !ok {
+		if m, ok := inst.(WillMount); ok {
+			m.ComponentWillMount()
+		}
+	}
+	core.applyUpdaters()
+	if d, ok := inst.(DerivedState); ok {
+		core.state = MergeState(core.state, d.DeriveState(props, core.state))
+	}
+	node = inst.Render(ctx, core.props, core.state)
+	if node == nil {
+		node = Empty()
+	}
+	childCtx = ctx
+	if c, ok := inst.(WithContext); ok {
+		childCtx = c.WithContext(ctx)
+	}
+	return node, childCtx, nil
+}
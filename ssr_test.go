@@ -0,0 +1,217 @@
+package greact
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// greeting sets its state in ComponentWillMount and must not be mounted on the
+// server.
+type greeting struct {
+	Core
+}
+
+func (g *greeting) ComponentWillMount() {
+	g.SetState(State{"greeting": "hello"})
+}
+
+func (g *greeting) ComponentDidMount() {
+	panic("ComponentDidMount called on the server")
+}
+
+func (g *greeting) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil,
+		NewNode(TextNode, "", state["greeting"].(string)+" "+props.String("name"), nil),
+	)
+}
+
+type panicky struct {
+	Core
+}
+
+func (panicky) Render(context.Context, Props, State) *Node {
+	panic("boom")
+}
+
+// doubled sets its state from its constructor and derives double from it.
+type doubled struct {
+	Core
+}
+
+func (doubled) New(Props) Component {
+	d := &doubled{}
+	d.SetState(State{"n": 2})
+	return d
+}
+
+func (d *doubled) DeriveState(props Props, state State) State {
+	n, _ := state["n"].(int)
+	return State{"double": n * 2}
+}
+
+func (d *doubled) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(TextNode, "", fmt.Sprint(state["double"]), nil)
+}
+
+func TestRenderStatic(t *testing.T) {
+	props := Props{"key": "a"}
+	node, _, err := renderStatic(context.Background(), doubled{}.New(props), props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Data != "4" {
+		t.Errorf("expected state to be derived from the constructor's got %s", node.Data)
+	}
+	if props["key"] != "a" {
+		t.Error("expected the props passed in to be left unchanged")
+	}
+}
+
+func TestVected_RenderToString(t *testing.T) {
+	v := New()
+	v.Register("greeting", &greeting{})
+	v.Register("clientonly", &ClientOnly{})
+	v.Register("panicky", &panicky{})
+	h := NewNode
+	node := h(ElementNode, "", "div", Attrs(
+		Attr("", "className", "app"),
+		Attr("", "title", `"a" < b`),
+		Attr("", "onClick", func([]Value) {}),
+		Attr("", "key", "root"),
+	),
+		h(ElementNode, "", "greeting", Attrs(Attr("", "name", "<world>"))),
+		h(ElementNode, "", "input", Attrs(
+			Attr("", "disabled", true),
+			Attr("", "checked", false),
+			Attr("", "aria-hidden", false),
+			Attr("", "maxlength", 10),
+		)),
+		h(ElementNode, "", "script", nil, h(TextNode, "", "a < b", nil)),
+		h(ElementNode, "", "clientonly", Attrs(Attr("", "fallback", h(TextNode, "", "loading", nil))),
+			h(ElementNode, "", "canvas", nil),
+		),
+	)
+	got, err := v.RenderToString(context.Background(), node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := func(n *Node) string {
+		return ` data-__vected_attr__="` + checksum(n) + `"`
+	}
+	p := h(ElementNode, "", "p", nil, h(TextNode, "", "hello <world>", nil))
+	expect := `<div class="app" title="&#34;a&#34; &lt; b"` + sum(node) + `>` +
+		`<p` + sum(p) + `>hello &lt;world&gt;</p>` +
+		`<input disabled aria-hidden="false" maxlength="10"` + sum(node.Children[1]) + `/>` +
+		`<script` + sum(node.Children[2]) + `>a < b</script>` +
+		`loading</div>`
+	if got != expect {
+		t.Errorf("expected %s got %s", expect, got)
+	}
+
+	// user data can't close the comment or raw text element it is written in.
+	script := h(ElementNode, "", "script", nil, h(TextNode, "", `x = "</script><img>"`, nil))
+	got, err = v.RenderToString(context.Background(), h(ElementNode, "", "div", nil,
+		h(CommentNode, "", "a --> b --- c", nil),
+		h(CommentNode, "", "->", nil),
+		script,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, `<!--a - -> b - - - c--><!-- ->-->`) ||
+		!strings.Contains(got, `>x = "<\/script><img>"</script>`) {
+		t.Errorf("expected comments and scripts to be escaped got %s", got)
+	}
+
+	_, err = v.RenderToString(context.Background(), h(ElementNode, "", "panicky", nil))
+	if err == nil || err.Error() != "greact: rendering panicky: boom" {
+		t.Errorf("expected the panic as an error got %v", err)
+	}
+}
+
+func TestVected_RenderToText(t *testing.T) {
+	v := New()
+	v.Register("greeting", &greeting{})
+	h := NewNode
+	li := func(text string, children ...*Node) *Node {
+		return h(ElementNode, "", "li", nil, append([]*Node{h(TextNode, "", text, nil)}, children...)...)
+	}
+	node := h(ElementNode, "", "div", Attrs(Attr("", "style", map[string]string{"color": "red"})),
+		h(ElementNode, "", "h1", nil, h(TextNode, "", "Order", nil)),
+		h(ElementNode, "", "greeting", Attrs(Attr("", "name", "<world>"))),
+		h(ElementNode, "", "style", nil, h(TextNode, "", "p { color: red }", nil)),
+		h(ElementNode, "", "ul", nil,
+			li("tea"),
+			li("cake", h(ElementNode, "", "ol", nil, li("flour"), li("sugar"))),
+		),
+		h(TextNode, "", "thanks", nil),
+	)
+	got, err := v.RenderToText(context.Background(), node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "Order\nhello <world>\n- tea\n- cake\n  1. flour\n  2. sugar\nthanks"
+	if got != expect {
+		t.Errorf("expected %q got %q", expect, got)
+	}
+}
+
+func TestVected_hydrationMismatch(t *testing.T) {
+	v := New()
+	doc := newObject()
+	v.Document = doc
+	var warnings []string
+	v.Warn = func(msg string) {
+		warnings = append(warnings, msg)
+	}
+	h := NewNode
+	p := func(text string) *Node {
+		return h(ElementNode, "", "p", nil, h(TextNode, "", text, nil))
+	}
+	node := h(ElementNode, "", "div", nil, p("same"), p("client"))
+
+	// markup as rendered on the server from a tree where the second paragraph
+	// says something else.
+	server := func(n *Node) *object {
+		e := doc.Call("createElement", n.Data).(*object)
+		e.Call("setAttribute", ServerAttr, checksum(n))
+		return e
+	}
+	div := server(node)
+	same := server(p("same"))
+	same.Call("appendChild", doc.Call("createTextNode", "same"))
+	stale := server(p("server"))
+	staleText := doc.Call("createTextNode", "server").(*object)
+	stale.Call("appendChild", staleText)
+	div.Call("appendChild", same)
+	div.Call("appendChild", stale)
+	el := newObject()
+	el.Call("appendChild", div)
+
+	out := v.Render(node, el, div).(*object)
+	if out != div || out.children[0] != same {
+		t.Error("expected matching server markup to be hydrated")
+	}
+	got := out.children[1]
+	if got == stale || got.children[0] == staleText {
+		t.Error("expected mismatched server markup to be rendered again")
+	}
+	if got.children[0].nodeValue != "client" {
+		t.Errorf("expected the client text got %q", got.children[0].nodeValue)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "<p>") {
+		t.Errorf("expected a warning about the mismatched paragraph got %q", warnings)
+	}
+
+	v.Dev = true
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a mismatch to panic in dev mode")
+		}
+	}()
+	el = newObject()
+	el.Call("appendChild", server(p("server")))
+	v.Render(p("client"), el, el.children[0])
+}
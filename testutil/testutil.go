@@ -0,0 +1,16 @@
+// Package testutil provides helpers for testing greact components.
+package testutil
+
+import (
+	vected "github.com/gernest/greact"
+)
+
+// Render renders a fresh instance of cmp with props and returns the virtual
+// tree produced by its Render method. No dom is involved so this is suited for
+// testing render logic, see greact.Shallow for details.
+func Render(cmp vected.Component, props vected.Props) (*vected.Node, error) {
+	if props == nil {
+		props = make(vected.Props)
+	}
+	return vected.Shallow(cmp, props)
+}
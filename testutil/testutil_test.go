@@ -0,0 +1,61 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	vected "github.com/gernest/greact"
+)
+
+type counter struct {
+	vected.Core
+}
+
+func (*counter) InitProps() vected.Props {
+	return vected.Props{"step": 1}
+}
+
+func (*counter) InitState() vected.State {
+	return vected.State{"count": 10}
+}
+
+func (c *counter) Render(ctx context.Context, props vected.Props, state vected.State) *vected.Node {
+	return vected.NewNode(vected.ElementNode, "", "button", vected.Attrs(
+		vected.Attr("", "data-step", props["step"]),
+	), vected.NewNode(vected.TextNode, "", fmt.Sprint(state["count"]), nil))
+}
+
+type broken struct {
+	vected.Core
+}
+
+func (*broken) Render(context.Context, vected.Props, vected.State) *vected.Node {
+	panic("boom")
+}
+
+func TestRender(t *testing.T) {
+	n, err := Render(&counter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Data != "button" {
+		t.Fatalf("expected button got %s", n.Data)
+	}
+	if got := n.Children[0].Data; got != "10" {
+		t.Errorf("expected initial count 10 got %s", got)
+	}
+	if got := n.Attr[0].Val; got != 1 {
+		t.Errorf("expected default step 1 got %v", got)
+	}
+	n, err = Render(&counter{}, vected.Props{"step": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.Attr[0].Val; got != 5 {
+		t.Errorf("expected step 5 got %v", got)
+	}
+	if _, err := Render(&broken{}, nil); err == nil {
+		t.Error("expected an error when Render panics")
+	}
+}
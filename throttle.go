@@ -0,0 +1,91 @@
+package greact
+
+import "sync"
+
+// OnScrollThrottled returns an onScroll attribute calling fn at most once per
+// frame. Scroll events fired in between are coalesced, fn receives the
+// arguments of the last one.
+func OnScrollThrottled(fn func([]Value)) Attribute {
+	return Attr("", "onScroll", &throttled{fn: fn})
+}
+
+// OnResizeThrottled returns an onResize attribute calling fn at most once per
+// frame, like OnScrollThrottled. resize events only fire on the window, fn is
+// called when the window is resized while the element is rendered.
+func OnResizeThrottled(fn func([]Value)) Attribute {
+	return Attr("", "onResize", &throttled{fn: fn})
+}
+
+// throttled is the value of attributes created by OnScrollThrottled and
+// OnResizeThrottled. The listener is only added to the dom once, so the state
+// is carried over from the value of the previous render.
+type throttled struct {
+	fn    func([]Value)
+	state *throttleState
+}
+
+type throttleState struct {
+	mu      sync.Mutex
+	v       *Vected
+	fn      func([]Value)
+	args    []Value
+	pending bool
+	stopped bool
+}
+
+// bind returns the event listener for t. old is the value of the attribute in
+// the previous render.
+func (t *throttled) bind(v *Vected, old interface{}) func([]Value) {
+	if prev, ok := old.(*throttled); ok && prev.state != nil {
+		t.state = prev.state
+	} else {
+		t.state = &throttleState{v: v}
+	}
+	t.state.mu.Lock()
+	t.state.fn = t.fn
+	t.state.mu.Unlock()
+	return t.state.handle
+}
+
+func (s *throttleState) handle(args []Value) {
+	s.mu.Lock()
+	s.args = args
+	if s.pending || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.pending = true
+	s.mu.Unlock()
+	s.v.schedule(s.run)
+}
+
+func (s *throttleState) run() {
+	s.mu.Lock()
+	fn, args, stopped := s.fn, s.args, s.stopped
+	s.pending = false
+	s.args = nil
+	s.mu.Unlock()
+	if stopped {
+		return
+	}
+	s.v.Batch(func() {
+		fn(args)
+	})
+}
+
+func (s *throttleState) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+}
+
+// releaseThrottled drops the pending calls of throttled listeners on elem.
+func (v *Vected) releaseThrottled(elem Element) {
+	if attrs, ok := v.cachedAttrs(elem); ok {
+		for _, a := range attrs {
+			if t, ok := a.Val.(*throttled); ok && t.state != nil {
+				t.state.stop()
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package greact
+
+// TraceKind is the decision taken by the reconciler for a virtual node.
+type TraceKind uint
+
+// supported trace kinds
+const (
+	// TraceCreate a new dom node was created.
+	TraceCreate TraceKind = iota
+
+	// TraceReplace a new dom node was created to replace an existing node of a
+	// different type.
+	TraceReplace
+
+	// TraceReuse an existing dom node was reused.
+	TraceReuse
+
+	// TraceUpdateText an existing text or comment node was reused and its value
+	// updated.
+	TraceUpdateText
+
+	// TraceRemove an unused dom node was removed.
+	TraceRemove
+
+	// TraceUpdateAttrs an existing element was reused and some of its
+	// attributes or event listeners changed.
+	TraceUpdateAttrs
+)
+
+func (k TraceKind) String() string {
+	switch k {
+	case TraceCreate:
+		return "create"
+	case TraceReplace:
+		return "replace"
+	case TraceReuse:
+		return "reuse"
+	case TraceUpdateText:
+		return "update-text"
+	case TraceRemove:
+		return "remove"
+	case TraceUpdateAttrs:
+		return "update-attrs"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceEvent describes a single decision taken while diffing.
+type TraceEvent struct {
+	Kind TraceKind
+
+	// Node is the virtual node that was diffed. This is nil for TraceRemove.
+	Node *Node
+
+	// Element is the resulting dom node, or the removed node for TraceRemove.
+	Element Element
+}
+
+// DiffStats counts the changes made to the dom by a render pass.
+type DiffStats struct {
+	// Created is the number of dom nodes created, including nodes that replaced
+	// a node of a different type.
+	Created int
+
+	// Updated is the number of text and comment nodes whose value changed, and
+	// of reused elements whose attributes or event listeners changed.
+	Updated int
+
+	// Moved is the number of existing nodes moved to a different position
+	// among their siblings.
+	Moved int
+
+	// Removed is the number of unused dom nodes removed.
+	Removed int
+}
+
+// trace reports a reconciler decision to v.Trace if it is set, and counts it in
+// v.stats.
+func (v *Vected) trace(kind TraceKind, node *Node, elem Element) {
+	switch kind {
+	case TraceCreate, TraceReplace:
+		v.stats.Created++
+	case TraceUpdateText, TraceUpdateAttrs:
+		v.stats.Updated++
+	case TraceRemove:
+		v.stats.Removed++
+	}
+	if v.Trace != nil {
+		v.Trace(TraceEvent{Kind: kind, Node: node, Element: elem})
+	}
+}
+
+// traceNew reports the creation of elem which replaced old if it was valid.
+func (v *Vected) traceNew(node *Node, elem, old Element) {
+	if Valid(old) {
+		v.trace(TraceReplace, node, elem)
+	} else {
+		v.trace(TraceCreate, node, elem)
+	}
+}
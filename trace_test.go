@@ -0,0 +1,36 @@
+package greact
+
+import "testing"
+
+func TestVected_Trace(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	var events []TraceEvent
+	v.Trace = func(e TraceEvent) {
+		events = append(events, e)
+	}
+	h := NewNode
+	node := h(ElementNode, "", "div", nil, h(TextNode, "", "hello", nil))
+	el := newObject()
+	div := v.Render(node, el)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events got %d", len(events))
+	}
+	for _, e := range events {
+		if e.Kind != TraceCreate {
+			t.Errorf("expected %s got %s", TraceCreate, e.Kind)
+		}
+	}
+	events = nil
+	v.Render(node, el, div)
+	if len(events) == 0 {
+		t.Fatal("expected events")
+	}
+	e := events[0]
+	if e.Kind != TraceReuse {
+		t.Errorf("expected %s got %s", TraceReuse, e.Kind)
+	}
+	if e.Node != node || !IsEqual(e.Element, div) {
+		t.Error("expected the event to identify the reused div")
+	}
+}
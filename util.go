@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"html"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/gernest/greact/attribute"
@@ -17,8 +18,10 @@ type object struct {
 	name      string
 	namespace string
 	text      bool
+	comment   bool
 	parent    *object
 	props     map[string]*object
+	attrs     map[string]string
 	value     interface{}
 	typ       Type
 	nodeValue string
@@ -26,6 +29,24 @@ type object struct {
 	children  []*object
 	journal   [][]interface{}
 	level     int
+	listeners map[string][]*callback
+	rect      Rect
+}
+
+// callback is the Resource returned by callbacks, objects call it when
+// dispatching events.
+type callback struct {
+	fn       func([]Value)
+	released bool
+}
+
+func (c *callback) Release() {
+	c.released = true
+}
+
+// callbacks is a CallbackGenerator for objects.
+func callbacks(fn func([]Value)) Resource {
+	return &callback{fn: fn}
 }
 
 func newObject() *object {
@@ -41,11 +62,21 @@ func (o *object) Bool() bool {
 }
 
 func (o *object) Float() float64 {
-	return o.value.(float64)
+	switch e := o.value.(type) {
+	case int:
+		return float64(e)
+	default:
+		return o.value.(float64)
+	}
 }
 
 func (o *object) Int() int {
-	return o.value.(int)
+	switch e := o.value.(type) {
+	case float64:
+		return int(e)
+	default:
+		return o.value.(int)
+	}
 }
 func (o *object) String() string {
 	return o.value.(string)
@@ -62,16 +93,26 @@ func (o *object) Set(k string, v interface{}) {
 	if o.props == nil {
 		o.props = make(map[string]*object)
 	}
+	if k == "nodeValue" {
+		o.nodeValue = fmt.Sprint(v)
+		return
+	}
 	switch e := v.(type) {
 	case bool:
 		o.props[k] = &object{typ: TypeBoolean, value: e}
 	case string:
 		o.props[k] = &object{typ: TypeString, value: e}
+	case int:
+		o.props[k] = &object{typ: TypeNumber, value: e}
 	case float64:
 		o.props[k] = &object{typ: TypeNumber, value: e}
 	case nil:
 		o.props[k] = &object{typ: TypeNull, value: e}
-	case Value:
+	case *object:
+		o.props[k] = e
+	case *callback:
+		o.props[k] = &object{typ: TypeFunction, value: e}
+	default:
 		o.props[k] = &object{typ: TypeObject, value: e}
 	}
 }
@@ -97,7 +138,7 @@ func (o *object) Get(k string) Value {
 				}
 			}
 		}
-		return undefined()
+		return null()
 	case "previousSibling":
 		if o.parent != nil {
 			for k, v := range o.parent.children {
@@ -109,19 +150,39 @@ func (o *object) Get(k string) Value {
 				}
 			}
 		}
-		return undefined()
+		return null()
+	case "firstChild":
+		if len(o.children) > 0 {
+			return o.children[0]
+		}
+		return null()
 	case "lastChild":
 		if len(o.children) > 0 {
 			return o.children[len(o.children)-1]
 		}
-		return undefined()
-	case "childNodes":
-		var children []Value
-		for _, ch := range o.children {
-			children = append(children, ch)
+		return null()
+	case "nodeType":
+		switch {
+		case o.text:
+			return &object{typ: TypeNumber, value: 3}
+		case o.comment:
+			return &object{typ: TypeNumber, value: 8}
+		default:
+			return &object{typ: TypeNumber, value: 1}
+		}
+	case "nodeName":
+		switch {
+		case o.text:
+			return &object{typ: TypeString, value: "#text"}
+		case o.comment:
+			return &object{typ: TypeString, value: "#comment"}
+		default:
+			return &object{typ: TypeString, value: strings.ToUpper(o.name)}
 		}
+	case "childNodes":
+		// like the dom, the returned list is live.
 		return &object{
-			value: children,
+			value: nodeList{o},
 			typ:   TypeObject,
 		}
 	case "length":
@@ -131,8 +192,24 @@ func (o *object) Get(k string) Value {
 		switch e := o.value.(type) {
 		case []Value:
 			return &object{typ: TypeNumber, value: len(e)}
+		case nodeList:
+			return &object{typ: TypeNumber, value: len(e.parent.children)}
 		}
 		return undefined()
+	case "attributes":
+		var keys []string
+		for k := range o.attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var attrs []Value
+		for _, k := range keys {
+			attrs = append(attrs, &object{typ: TypeObject, props: map[string]*object{
+				"name":  {typ: TypeString, value: k},
+				"value": {typ: TypeString, value: o.attrs[k]},
+			}})
+		}
+		return &object{typ: TypeObject, value: attrs}
 	case "splitText":
 		if o.text {
 			return &object{typ: TypeFunction}
@@ -140,6 +217,8 @@ func (o *object) Get(k string) Value {
 		return undefined()
 	case "nodeValue":
 		return &object{typ: TypeString, value: o.nodeValue}
+	case "textContent":
+		return &object{typ: TypeString, value: o.textContent()}
 	}
 	if m, ok := o.props[k]; ok {
 		return m
@@ -151,11 +230,7 @@ func (o *object) Call(k string, args ...interface{}) Value {
 	v := []interface{}{"call", k}
 	for _, k := range args {
 		if o, ok := k.(*object); ok {
-			v = append(v, o.typ)
-			s := strings.TrimSpace(o.Steps())
-			if s != "" {
-				v = append(v, s)
-			}
+			v = append(v, o.typ, o.id)
 		} else {
 			v = append(v, k)
 		}
@@ -190,13 +265,20 @@ func (o *object) Call(k string, args ...interface{}) Value {
 		b.text = true
 		b.nodeValue = text
 		return b
+	case "createComment":
+		b := newObject()
+		b.comment = true
+		if len(args) > 0 {
+			b.nodeValue = args[0].(string)
+		}
+		return b
 	case "replaceChild":
 		if len(args) == 2 {
 			a, ok := args[0].(*object)
 			if !ok {
 				return undefined()
 			}
-			b, ok := args[0].(*object)
+			b, ok := args[1].(*object)
 			if !ok {
 				return undefined()
 			}
@@ -208,15 +290,7 @@ func (o *object) Call(k string, args ...interface{}) Value {
 			if !ok {
 				return undefined()
 			}
-			if len(o.children) > 0 {
-				var sv []*object
-				for _, v := range o.children {
-					if v.id != a.id {
-						sv = append(sv, v)
-					}
-				}
-				o.children = sv
-			}
+			o.removeChild(a)
 		}
 	case "appendChild":
 		if len(args) == 1 {
@@ -224,6 +298,7 @@ func (o *object) Call(k string, args ...interface{}) Value {
 			if !ok {
 				return undefined()
 			}
+			a.detach()
 			a.parent = o
 			a.level = o.level + 2
 			o.children = append(o.children, a)
@@ -235,12 +310,76 @@ func (o *object) Call(k string, args ...interface{}) Value {
 			if !ok {
 				return undefined()
 			}
-			b, ok := args[0].(*object)
-			if !ok {
-				return undefined()
+			b, ok := args[1].(*object)
+			if !ok || !Valid(b) {
+				return o.Call("appendChild", a)
 			}
 			return o.insertBefore(a, b)
 		}
+	case "setAttribute":
+		if len(args) == 2 {
+			if o.attrs == nil {
+				o.attrs = make(map[string]string)
+			}
+			o.attrs[args[0].(string)] = fmt.Sprint(args[1])
+		}
+		return undefined()
+	case "removeAttribute":
+		if len(args) == 1 {
+			delete(o.attrs, args[0].(string))
+		}
+		return undefined()
+	case "getAttribute":
+		if len(args) == 1 {
+			if v, ok := o.attrs[args[0].(string)]; ok {
+				return &object{typ: TypeString, value: v}
+			}
+		}
+		return null()
+	case "hasAttribute":
+		if len(args) == 1 {
+			_, ok := o.attrs[args[0].(string)]
+			return &object{typ: TypeBoolean, value: ok}
+		}
+		return &object{typ: TypeBoolean, value: false}
+	case "getBoundingClientRect":
+		r := newObject()
+		r.Set("left", o.rect.Left)
+		r.Set("top", o.rect.Top)
+		r.Set("width", o.rect.Width)
+		r.Set("height", o.rect.Height)
+		return r
+	case "addEventListener":
+		if cb, ok := args[1].(*callback); ok {
+			if o.listeners == nil {
+				o.listeners = make(map[string][]*callback)
+			}
+			name := args[0].(string)
+			o.listeners[name] = append(o.listeners[name], cb)
+		}
+		return undefined()
+	case "removeEventListener":
+		name := args[0].(string)
+		for i, cb := range o.listeners[name] {
+			if cb == args[1] {
+				o.listeners[name] = append(o.listeners[name][:i:i], o.listeners[name][i+1:]...)
+				break
+			}
+		}
+		return undefined()
+	default:
+		if p, ok := o.props[k]; ok {
+			if cb, ok := p.value.(*callback); ok {
+				var a []Value
+				for _, v := range args {
+					if v, ok := v.(Value); ok {
+						a = append(a, v)
+					}
+				}
+				cb.fn(a)
+			}
+		}
+		return undefined()
 	case "isEqualNode":
 		if len(args) == 1 {
 			a, ok := args[0].(*object)
@@ -254,6 +393,15 @@ func (o *object) Call(k string, args ...interface{}) Value {
 	return undefined()
 }
 
+// dispatch calls the listeners for the event name on o and its ancestors.
+func (o *object) dispatch(name string, ev Value) {
+	for n := o; n != nil; n = n.parent {
+		for _, cb := range append([]*callback(nil), n.listeners[name]...) {
+			cb.fn([]Value{ev})
+		}
+	}
+}
+
 func (o object) Steps() string {
 	var buf bytes.Buffer
 	for _, v := range o.journal {
@@ -267,6 +415,19 @@ func (o object) Steps() string {
 	return buf.String()
 }
 
+func (o *object) textContent() string {
+	if o.text {
+		return o.nodeValue
+	}
+	var s strings.Builder
+	for _, ch := range o.children {
+		if !ch.comment {
+			s.WriteString(ch.textContent())
+		}
+	}
+	return s.String()
+}
+
 func validAttribute(v string) bool {
 	_, ok := attribute.Map[v]
 	return ok
@@ -279,35 +440,55 @@ func indent(n int) (out string) {
 	return
 }
 
-func (o *object) replaceChild(a, b *object) *object {
-	if len(o.children) > 0 {
-		var rst []*object
-		for _, v := range o.children {
-			if v.id == a.id {
-				rst = append(rst, b)
-			} else {
-				rst = append(rst, v)
-			}
+// replaceChild replaces old child with a.
+func (o *object) replaceChild(a, old *object) *object {
+	a.detach()
+	for k, v := range o.children {
+		if v.id == old.id {
+			o.children[k] = a
+			a.parent = o
+			a.level = o.level + 2
+			old.parent = nil
+			break
 		}
-		o.children = rst
 	}
 	return undefined()
 }
-func (o *object) insertBefore(a, b *object) *object {
-	if len(o.children) > 0 {
-		var rst []*object
-		for _, v := range o.children {
-			if v.id == a.id {
-				rst = append(rst, b, a)
-			} else {
-				rst = append(rst, v)
-			}
+
+// insertBefore inserts a before the child ref.
+func (o *object) insertBefore(a, ref *object) *object {
+	a.detach()
+	var rst []*object
+	for _, v := range o.children {
+		if v.id == ref.id {
+			rst = append(rst, a)
+			a.parent = o
+			a.level = o.level + 2
 		}
-		o.children = rst
+		rst = append(rst, v)
 	}
+	o.children = rst
 	return undefined()
 }
 
+func (o *object) removeChild(a *object) {
+	var rst []*object
+	for _, v := range o.children {
+		if v.id != a.id {
+			rst = append(rst, v)
+		}
+	}
+	o.children = rst
+	a.parent = nil
+}
+
+// detach removes o from its parent if any.
+func (o *object) detach() {
+	if o.parent != nil {
+		o.parent.removeChild(o)
+	}
+}
+
 func undefined() *object {
 	return &object{typ: TypeUndefined}
 }
@@ -315,10 +496,20 @@ func null() *object {
 	return &object{typ: TypeNull}
 }
 
+// nodeList is the value of the childNodes of parent.
+type nodeList struct {
+	parent *object
+}
+
 func (o *object) Index(n int) Value {
-	if v, ok := o.value.([]Value); ok {
-		if n < len(v) {
-			return v[n]
+	switch e := o.value.(type) {
+	case []Value:
+		if n < len(e) {
+			return e[n]
+		}
+	case nodeList:
+		if n < len(e.parent.children) {
+			return e.parent.children[n]
 		}
 	}
 	return &object{typ: TypeNull}
@@ -393,6 +584,10 @@ func render1(w writer, n *object) error {
 		_, err := w.WriteString(e)
 		return err
 	}
+	if n.comment {
+		_, err := fmt.Fprintf(w, "<!--%s-->", n.nodeValue)
+		return err
+	}
 
 	// Render the <xxx> opening tag.
 	if err := w.WriteByte('<'); err != nil {
@@ -474,6 +669,16 @@ func render1(w writer, n *object) error {
 			}
 		}
 	}
+	var attrs []string
+	for key := range n.attrs {
+		attrs = append(attrs, key)
+	}
+	sort.Strings(attrs)
+	for _, key := range attrs {
+		if _, err := fmt.Fprintf(w, ` %s="%s"`, key, html.EscapeString(n.attrs[key])); err != nil {
+			return err
+		}
+	}
 	if voidElements[n.name] {
 		if len(n.children) > 0 {
 			return fmt.Errorf("html: void element <%s> has child nodes", n.name)
@@ -498,31 +703,22 @@ func render1(w writer, n *object) error {
 	}
 
 	// Render any child nodes.
-	switch n.name {
-	case "iframe", "noembed", "noframes", "noscript", "plaintext", "script", "style", "xmp":
-		for _, c := range n.children {
-			if c.text {
-				if _, err := w.WriteString(c.nodeValue); err != nil {
-					return err
-				}
-			} else {
-				if err := render1(w, c); err != nil {
-					return err
-				}
-			}
-		}
-		if n.name == "plaintext" {
-			// Don't render anything else. <plaintext> must be the
-			// last element in the file, with no closing tag.
-			return plaintextAbort
-		}
-	default:
-		for _, c := range n.children {
-			if err := render1(w, c); err != nil {
+	for _, c := range n.children {
+		if c.text {
+			if _, err := w.WriteString(escapeText(n.name, c.nodeValue)); err != nil {
 				return err
 			}
+			continue
+		}
+		if err := render1(w, c); err != nil {
+			return err
 		}
 	}
+	if n.name == "plaintext" {
+		// Don't render anything else. <plaintext> must be the
+		// last element in the file, with no closing tag.
+		return plaintextAbort
+	}
 
 	// Render the </xxx> closing tag.
 	if _, err := w.WriteString("</"); err != nil {
@@ -575,3 +771,41 @@ var voidElements = map[string]bool{
 	"track":   true,
 	"wbr":     true,
 }
+
+// rawTextElements are elements whose text content is written as is. The
+// browser doesn't decode entities inside them, so escaping would change the
+// content of scripts and styles.
+var rawTextElements = map[string]bool{
+	"iframe":    true,
+	"noembed":   true,
+	"noframes":  true,
+	"noscript":  true,
+	"plaintext": true,
+	"script":    true,
+	"style":     true,
+	"xmp":       true,
+}
+
+// escapeText returns text escaped according to the rules of the parent
+// element. Everything including escapable raw text elements like textarea and
+// title is html escaped, except text inside raw text elements where only </ is
+// written as <\/ so the text can't close the element.
+func escapeText(parent, text string) string {
+	if rawTextElements[parent] {
+		return strings.Replace(text, "</", `<\/`, -1)
+	}
+	return html.EscapeString(text)
+}
+
+// escapeComment returns text changed so that it can't end the html comment it
+// is written in. Every -- is broken up and a leading > or -> is preceded by a
+// space.
+func escapeComment(text string) string {
+	for strings.Contains(text, "--") {
+		text = strings.Replace(text, "--", "- -", -1)
+	}
+	if strings.HasPrefix(text, ">") || strings.HasPrefix(text, "->") {
+		text = " " + text
+	}
+	return text
+}
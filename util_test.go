@@ -1,6 +1,13 @@
 package greact
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
+
+// object is the in memory dom backend used by tests, it must implement the
+// same interface as any other backend.
+var _ Element = (*object)(nil)
 
 func TestObject(t *testing.T) {
 	t.Run("hasOwnProperty", func(ts *testing.T) {
@@ -14,3 +21,30 @@ func TestObject(t *testing.T) {
 		}
 	})
 }
+
+func TestRenderObject(t *testing.T) {
+	doc := newObject()
+	render := func(name, text string) string {
+		e := doc.Call("createElement", name)
+		e.Call("appendChild", doc.Call("createTextNode", text))
+		var buf bytes.Buffer
+		if err := renderObject(&buf, e.(*object)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	sample := []struct {
+		name, text, expect string
+	}{
+		{"div", "a < b && c", "<div>a &lt; b &amp;&amp; c</div>"},
+		{"script", "if (a < b && c) {}", "<script>if (a < b && c) {}</script>"},
+		{"style", "a > b {}", "<style>a > b {}</style>"},
+		{"textarea", "<b>", "<textarea>&lt;b&gt;</textarea>"},
+	}
+	for _, v := range sample {
+		got := render(v.name, v.text)
+		if got != v.expect {
+			t.Errorf("expected %s got %s", v.expect, got)
+		}
+	}
+}
@@ -4,17 +4,27 @@
 // This relies on the experimental wasm api to interact with dom. The project
 // started as a port of preact to go, but has since evolved. It still borrows a
 // similar API from react/preact.
+//
+// The components provided by the package, like Freeze, Collapse or Draggable,
+// are registered with Vected.Register under a name of your choosing, like any
+// other component.
 package greact
 
 import (
 	"container/list"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gernest/greact/elements"
 )
@@ -32,16 +42,33 @@ const (
 
 // AttrKey is a key used to store node's attributes/props
 const AttrKey = "__vected_attr__"
-const svg = "http://www.w3.org/2000/svg'"
+
+// keyKey is used to store the key of keyed elements.
+const keyKey = "__vected_key__"
+
+// dom node types as reported by the nodeType property.
+const (
+	elementNodeType = 1
+	textNodeType    = 3
+	commentNodeType = 8
+)
+
+// supported namespaces for the root of a render.
+const (
+	HTMLNamespace   = "http://www.w3.org/1999/xhtml"
+	SVGNamespace    = "http://www.w3.org/2000/svg"
+	MathMLNamespace = "http://www.w3.org/1998/Math/MathML"
+)
 
 // This tracks the last id issued. We use sync pool to reuse component id's.
+// idx is updated atomically so ids are unique even when components are
+// created concurrently.
 //
 // TODO: come up with a better way that can scale.
-var idx int
+var idx int64
 var idPool = &sync.Pool{
 	New: func() interface{} {
-		idx++
-		return idx
+		return int(atomic.AddInt64(&idx, 1))
 	},
 }
 
@@ -116,17 +143,33 @@ func (t Type) String() string {
 	}
 }
 
-// Value is an interface for a javascript value.
+// Value is an interface for a javascript value. It mirrors syscall/js.Value so
+// a wasm backend is a thin wrapper, other backends can implement it to render
+// to something other than the browser dom.
 type Value interface {
+	// Bool, Float, Int and String return the value as the respective go type.
 	Bool() bool
-	Call(m string, args ...interface{}) Value
 	Float() float64
-	Get(string) Value
-	Index(int) Value
 	Int() int
+	String() string
+
+	// Call calls method m of the value with args.
+	Call(m string, args ...interface{}) Value
+
+	// Get returns property p of the value, missing properties must be returned
+	// as a Value whose Type is TypeUndefined or TypeNull, never as nil.
+	Get(p string) Value
+
+	// Index returns the element at index i of an array like value.
+	Index(i int) Value
+
+	// Invoke calls the value as a function.
 	Invoke(args ...interface{}) Value
+
+	// Set sets property p of the value to x.
 	Set(p string, x interface{})
-	String() string
+
+	// Type returns the javascript type of the value.
 	Type() Type
 }
 
@@ -174,7 +217,17 @@ func RemoveNode(node Value) {
 	}
 }
 
-// Element is an alias for the dom node.
+// Element is a dom node. Backends implement Value with these dom features
+// which are used while diffing.
+//
+// 	properties: parentNode, firstChild, lastChild, nextSibling, childNodes,
+// 	            nodeType, nodeName, nodeValue, splitText, attributes,
+// 	            textContent
+// 	document:   createElement, createElementNS, createTextNode, createComment
+// 	nodes:      appendChild, insertBefore, removeChild, replaceChild,
+// 	            setAttribute, getAttribute, removeAttribute, hasAttribute,
+// 	            addEventListener, removeEventListener, isEqualNode,
+// 	            hasOwnProperty
 type Element Value
 
 // HasProperty returns true if e has property.
@@ -203,6 +256,33 @@ type Constructor interface {
 	New(Props) Component
 }
 
+// DisplayName is an interface for components that want to be shown under a
+// different name than the one they were registered with, for instance in
+// warnings and debugging tools.
+type DisplayName interface {
+	DisplayName() string
+}
+
+// Name returns the name used to display cmp. This is the value returned by
+// DisplayName when cmp implements it, otherwise the constructor name.
+func Name(cmp Component) string {
+	if d, ok := cmp.(DisplayName); ok {
+		if name := d.DisplayName(); name != "" {
+			return name
+		}
+	}
+	return cmp.core().constructor
+}
+
+// SameComponent returns true if a and b are the same component instance. Use
+// it to check that an instance was reused across renders.
+func SameComponent(a, b Component) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.core().id != 0 && a.core().id == b.core().id
+}
+
 // Core is th base struct that every struct that wants to implement Component
 // interface must embed.
 //
@@ -224,10 +304,19 @@ type Core struct {
 	prevProps   Props
 	prevState   State
 
+	// values used by the previous render, exposed by PrevProps, PrevState and
+	// PrevContext.
+	previousContext context.Context
+	previousProps   Props
+	previousState   State
+
 	// A list of functions that will be called after the component has been
 	// rendered.
 	renderCallbacks []func()
 
+	// updaters are functions passed to SetStateFunc waiting to be applied.
+	updaters []func(State) State
+
 	// This is the instance of the child component.
 	component       Component
 	parentComponent Component
@@ -238,6 +327,17 @@ type Core struct {
 	base     Element
 	nextBase Element
 
+	// rendered is the tree returned by the last call to Render. It is only
+	// tracked when the Vected's PoolNodes is enabled.
+	rendered *Node
+
+	// memoize enables reusing memo when the component is rendered again with
+	// the same props and state, see Memoize.
+	memoize   bool
+	memo      *Node
+	memoProps Props
+	memoState State
+
 	dirty   bool
 	disable bool
 
@@ -249,8 +349,19 @@ type Core struct {
 	// after they have been mounted.
 	ref func(interface{})
 
+	// named refs, see Ref.
+	refs map[string]interface{}
+
+	// owner is the component whose render created this component and index is
+	// the position of the component among its siblings, -1 for an only child.
+	// They are used to compute path.
+	owner Component
+	index int
+	path  string
+
 	// priority this is a number indicating how important this component is in the
-	// re rendering queue. The higher the number the more urgent re renders.
+	// re rendering queue. The higher the number the more urgent re renders. It
+	// is set with SetPriority.
 	priority int
 
 	enqueue *queuedRender
@@ -258,15 +369,252 @@ type Core struct {
 
 func (c *Core) core() *Core { return c }
 
+// ID returns the id identifying the component instance, it is 0 until the
+// component is created by Vected.
+func (c *Core) ID() int {
+	return c.id
+}
+
 // SetState updates component state and schedule re rendering.
+//
+// Calling SetState before the component is mounted, for instance from
+// ComponentWillMount, only merges newState into the state used by the first
+// render.
 func (c *Core) SetState(newState State, callback ...func()) {
-	prev := c.prevState
-	c.prevState = newState
-	c.state = MergeState(prev, newState)
+	c.SetStateMode(Async, newState, callback...)
+}
+
+// SetStateMode is like SetState but mode decides how the component is re
+// rendered. Sync and Force render the component before SetStateMode returns,
+// Force skips ShouldComponentUpdate, Async enqueues the component and No only
+// updates the state.
+func (c *Core) SetStateMode(mode RenderMode, newState State, callback ...func()) {
+	if c.prevState == nil {
+		c.prevState = c.state
+	}
+	if len(c.updaters) > 0 {
+		// apply after the pending updaters to keep the order of updates.
+		c.updaters = append(c.updaters, func(State) State {
+			return newState
+		})
+	} else {
+		c.state = MergeState(c.state, newState)
+	}
 	if len(callback) > 0 {
 		c.renderCallbacks = append(c.renderCallbacks, callback...)
 	}
-	c.enqueue.enqueueCore(c)
+	if c.base == nil || c.enqueue == nil || c.disable {
+		// not mounted yet, or disabled
+		return
+	}
+	switch mode {
+	case Sync, Force:
+		c.render(mode)
+	case Async:
+		c.enqueue.enqueueCore(c)
+	}
+}
+
+// SetStateFunc is like SetState but the new state is computed by updater from
+// the latest state when the component is rendered, instead of the state at the
+// time of the call. Use it when the next state depends on the current one.
+//
+// 	c.SetStateFunc(func(s greact.State) greact.State {
+// 		return greact.State{"count": s["count"].(int) + 1}
+// 	})
+//
+// Updaters are applied in order, the result of each is merged into the state
+// before the next is called.
+func (c *Core) SetStateFunc(updater func(State) State, callback ...func()) {
+	if c.prevState == nil {
+		c.prevState = c.state
+	}
+	c.renderCallbacks = append(c.renderCallbacks, callback...)
+	if c.base == nil || c.enqueue == nil {
+		// not mounted yet
+		c.state = MergeState(c.state, updater(c.state))
+		return
+	}
+	c.updaters = append(c.updaters, updater)
+	if !c.disable {
+		c.enqueue.enqueueCore(c)
+	}
+}
+
+// applyUpdaters applies pending updaters to the state.
+func (c *Core) applyUpdaters() {
+	updaters := c.updaters
+	c.updaters = nil
+	for _, fn := range updaters {
+		c.state = MergeState(c.state, fn(c.state))
+	}
+}
+
+// ForceUpdate re renders the component synchronously, skipping
+// ShouldComponentUpdate. This does nothing if the component isn't mounted.
+func (c *Core) ForceUpdate(callback ...func()) {
+	if len(callback) > 0 {
+		c.renderCallbacks = append(c.renderCallbacks, callback...)
+	}
+	if c.base == nil || c.enqueue == nil {
+		return
+	}
+	c.render(Force)
+}
+
+func (c *Core) render(mode RenderMode) {
+	v := c.enqueue.v
+	v.renderComponent(v.cache[c.id], mode, false, false)
+}
+
+// Disable stops the component from rendering. State changes are kept but
+// don't re render the component and updates from its parent are ignored, until
+// Enable is called.
+func (c *Core) Disable() {
+	c.disable = true
+}
+
+// Enable undoes Disable, a mounted component is rendered synchronously to catch
+// up with state changes made while it was disabled.
+func (c *Core) Enable() {
+	if !c.disable {
+		return
+	}
+	c.disable = false
+	if c.base != nil && c.enqueue != nil {
+		c.render(Sync)
+	}
+}
+
+// SetPriority sets how urgent re rendering the component is, queued
+// components with a higher priority are rendered first. The default is 0.
+func (c *Core) SetPriority(priority int) {
+	c.priority = priority
+}
+
+// Memoize when on makes the component reuse the tree returned by its last
+// Render call instead of calling Render again, as long as props and state are
+// deeply equal to the ones of that call. Only use this for components whose
+// Render depends on nothing but props and state.
+func (c *Core) Memoize(on bool) {
+	c.memoize = on
+	if !on {
+		c.memo = nil
+		c.memoProps = nil
+		c.memoState = nil
+	}
+}
+
+// Ref returns a callback that can be passed as the ref attribute of a child
+// element or component. The value it receives is stored under name and can be
+// read back with RefOf once the child is mounted.
+//
+// 	NewNode(ElementNode, "", "input", Attrs(Attr("", "ref", c.Ref("input"))))
+func (c *Core) Ref(name string) func(interface{}) {
+	return func(v interface{}) {
+		if v == nil {
+			delete(c.refs, name)
+			return
+		}
+		if c.refs == nil {
+			c.refs = make(map[string]interface{})
+		}
+		c.refs[name] = v
+	}
+}
+
+// RefOf returns the value stored by the callback returned by Ref(name), this
+// is nil when nothing with that ref is mounted.
+func (c *Core) RefOf(name string) interface{} {
+	return c.refs[name]
+}
+
+// Ref holds the value received by a ref callback. Pass its Set method as the
+// ref attribute of an element or component, Current is then the mounted
+// Element or Component and nil once it is unmounted.
+//
+// 	var input greact.Ref
+// 	NewNode(ElementNode, "", "input", Attrs(Attr("", "ref", input.Set)))
+type Ref struct {
+	Current interface{}
+}
+
+// Set stores v in Current.
+func (r *Ref) Set(v interface{}) {
+	r.Current = v
+}
+
+// Path returns the names of the components from the root to this one, like
+// App > List > Item[2]. The position among siblings is only shown when the
+// component has siblings. This is meant for logs and warnings.
+func (c *Core) Path() string {
+	if c.path == "" {
+		c.path = c.segment()
+		if c.owner != nil {
+			c.path = c.owner.core().Path() + " > " + c.path
+		}
+	}
+	return c.path
+}
+
+func (c *Core) segment() string {
+	name := c.constructor
+	if c.enqueue != nil {
+		if cmp, ok := c.enqueue.v.cache[c.id]; ok {
+			name = Name(cmp)
+		}
+	}
+	if c.index >= 0 {
+		return fmt.Sprintf("%s[%d]", name, c.index)
+	}
+	return name
+}
+
+// pendingCallbacks returns the number of SetState callbacks waiting for the
+// next render.
+func (c *Core) pendingCallbacks() int {
+	return len(c.renderCallbacks)
+}
+
+// ClassFromState returns a space separated list of the classes in classes
+// whose state key is true in the current state. classes maps a class name to
+// the state key that toggles it, classes are sorted.
+//
+// 	c.ClassFromState(map[string]string{"is-active": "active"})
+func (c *Core) ClassFromState(classes map[string]string) string {
+	var names []string
+	for class, key := range classes {
+		if on, ok := c.state[key].(bool); ok && on {
+			names = append(names, class)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// IsMounting returns true until the component has been rendered into the dom
+// for the first time. Use it in Render and lifecycle methods to tell the
+// initial mount apart from updates.
+func (c *Core) IsMounting() bool {
+	return c.base == nil
+}
+
+// PrevProps returns props of the previous render, this is nil until the
+// component is updated.
+func (c *Core) PrevProps() Props {
+	return c.previousProps
+}
+
+// PrevState returns state of the previous render, this is nil until the
+// component is updated.
+func (c *Core) PrevState() State {
+	return c.previousState
+}
+
+// PrevContext returns context of the previous render, this is nil until the
+// component is updated.
+func (c *Core) PrevContext() context.Context {
+	return c.previousContext
 }
 
 // Props returns current props.s
@@ -316,15 +664,20 @@ type WillUnmount interface {
 }
 
 // WillReceiveProps is an interface defining a callback that will be called with
-// the new props before they are accepted and passed to be rendered.
+// the new props before they are accepted and passed to be rendered. It is only
+// called when a mounted component is updated by its parent, Props still
+// returns the current props while it runs.
 type WillReceiveProps interface {
 	ComponentWillReceiveProps(context.Context, Props)
 }
 
 // ShouldUpdate is an interface defining callback that is called before render
-// determine if re render is necessary.
+// determine if re render is necessary. It is called with the next props and
+// state for updates only, not on mount and not when rendering with Force, for
+// instance from ForceUpdate.
 type ShouldUpdate interface {
-	// If this returns false then re rendering for the component is skipped.
+	// If this returns false then re rendering for the component is skipped,
+	// the next props and state are still stored.
 	ShouldComponentUpdate(context.Context, Props, State) bool
 }
 
@@ -340,7 +693,24 @@ type DidUpdate interface {
 	ComponentDidUpdate(prevProps Props, prevState State)
 }
 
+// ErrorBoundary is an interface for components that catch panics raised while
+// rendering their children. ComponentDidCatch is called with the error and the
+// component is rendered again, it should update its state so that Render
+// returns a fallback tree.
+//
+// A boundary doesn't catch its own errors, these go to the next boundary up
+// the tree.
+type ErrorBoundary interface {
+	ComponentDidCatch(err error, info ErrorInfo)
+}
+
 // DerivedState is an interface which can be used to derive state from props.
+// DeriveState is called before every render, on mount and on updates, with the
+// next props and state. The returned state is merged into the state before
+// ShouldComponentUpdate is called.
+//
+// Components implementing it don't receive ComponentWillMount and
+// ComponentWillReceiveProps.
 type DerivedState interface {
 	DeriveState(Props, State) State
 }
@@ -351,27 +721,77 @@ type WithContext interface {
 	WithContext(context.Context) context.Context
 }
 
+// DefaultQueueSize is the default maximum number of components waiting in the
+// render queue.
+const DefaultQueueSize = 1 << 12
+
 type queuedRender struct {
 	components *list.List
-	mu         sync.RWMutex
-	closed     bool
-	v          *Vected
+
+	// queued maps component ids to their position in components, a component
+	// is only queued once.
+	queued map[int]*list.Element
+
+	// size is the maximum number of queued components, components pushed to a
+	// full queue are dropped.
+	size   int
+	mu     sync.RWMutex
+	closed bool
+	v      *Vected
+
+	// draining is true while the queue is being drained, components queued in
+	// the meantime are rendered by the running drain.
+	draining bool
+
+	// paused stops the queue from being drained, see Vected.Pause.
+	paused bool
+
+	// scheduled is true when a drain has been scheduled and hasn't started yet.
+	scheduled bool
+
+	// batching is the depth of nested Batch calls. The queue can be drained
+	// from the scheduler's goroutine, so it is guarded by mu like the rest.
+	batching int
 }
 
 func newQueuedRender(v *Vected) *queuedRender {
 	return &queuedRender{
 		components: list.New(),
+		queued:     make(map[int]*list.Element),
+		size:       DefaultQueueSize,
 		v:          v,
 	}
 }
 
-func (q *queuedRender) Push(v Component) {
+// Len returns the number of queued components.
+func (q *queuedRender) Len() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.components.Len()
+}
+
+// Push adds v to the queue and returns true if it was added. Components that
+// are already queued are ignored, and when the queue is full v is dropped with
+// a warning.
+func (q *queuedRender) Push(v Component) bool {
 	q.mu.Lock()
-	defer q.mu.Unlock()
-	q.components.PushBack(v)
+	id := v.core().id
+	if _, ok := q.queued[id]; ok {
+		q.mu.Unlock()
+		return false
+	}
+	if q.size > 0 && q.components.Len() >= q.size {
+		q.mu.Unlock()
+		q.v.warn("greact: render queue is full, dropping " + v.core().Path())
+		return false
+	}
+	q.queued[id] = q.components.PushBack(v)
+	q.mu.Unlock()
+	return true
 }
 
-// Pop returns the last added component and removes it from the queue.
+// Pop returns the component with the highest priority and removes it from the
+// queue. Among components with the same priority the first added is returned.
 func (q *queuedRender) Pop() Component {
 	e := q.pop()
 	if e != nil {
@@ -381,12 +801,23 @@ func (q *queuedRender) Pop() Component {
 }
 
 func (q *queuedRender) pop() *list.Element {
-	e := q.last()
 	q.mu.Lock()
-	if e != nil {
-		q.components.Remove(e)
+	defer q.mu.Unlock()
+	return q.popLocked()
+}
+
+func (q *queuedRender) popLocked() *list.Element {
+	e := q.components.Front()
+	if e == nil {
+		return nil
 	}
-	q.mu.Unlock()
+	for p := e.Next(); p != nil; p = p.Next() {
+		if p.Value.(Component).core().priority > e.Value.(Component).core().priority {
+			e = p
+		}
+	}
+	q.components.Remove(e)
+	delete(q.queued, e.Value.(Component).core().id)
 	return e
 }
 
@@ -406,34 +837,99 @@ func (q *queuedRender) Last() Component {
 	return nil
 }
 
-// Rerender re renders all enqueued dirty components async.
+// Rerender schedules rendering of all enqueued dirty components. Only one
+// drain is scheduled at a time, so components queued before it runs are
+// rendered together. This does nothing inside Batch, the queue is flushed
+// when the batch is done.
 func (q *queuedRender) Rerender() {
-	go q.rerender()
+	q.mu.Lock()
+	if q.batching > 0 {
+		q.mu.Unlock()
+		return
+	}
+	scheduled := q.scheduled
+	q.scheduled = true
+	q.mu.Unlock()
+	if !scheduled {
+		q.v.schedule(q.scheduledRerender)
+	}
+}
+
+// batch adds n to the depth of nested Batch calls and returns the new depth.
+func (q *queuedRender) batch(n int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batching += n
+	return q.batching
+}
+
+func (q *queuedRender) scheduledRerender() {
+	q.mu.Lock()
+	q.scheduled = false
+	q.mu.Unlock()
+	q.rerender()
 }
 
+// enqueue marks cmp dirty and queues it. The queue is only drained when cmp
+// wasn't already waiting, so a burst of updates renders cmp once.
 func (q *queuedRender) enqueue(cmp Component) {
-	if !cmp.core().dirty {
-		cmp.core().dirty = true
+	if cmp.core().disable {
+		return
+	}
+	cmp.core().dirty = true
+	if q.Push(cmp) {
+		q.Rerender()
 	}
-	q.Push(cmp)
-	q.Rerender()
 }
 
 func (q *queuedRender) enqueueCore(core *Core) {
-	cmp := q.v.cache[core.id]
-	if !cmp.core().dirty {
-		cmp.core().dirty = true
+	q.enqueue(q.v.cache[core.id])
+}
+
+// next removes and returns the next component to render. The drain ends when
+// the queue is empty or paused, in the same critical section so that a
+// component pushed by a caller who saw the drain running isn't left behind.
+// stats is set to the changes made by the drain when it ends.
+func (q *queuedRender) next(stats *DiffStats) Component {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.paused {
+		if e := q.popLocked(); e != nil {
+			return e.Value.(Component)
+		}
 	}
-	q.Push(cmp)
-	q.Rerender()
+	*stats = q.v.stats
+	q.draining = false
+	return nil
 }
 
-func (q *queuedRender) rerender() {
-	for cmp := q.Pop(); cmp != nil; cmp = q.Pop() {
+// rerender drains the queue. It returns right away when a drain is already
+// running, for instance when an event handler fired during a render ends its
+// Batch, the running drain renders what was queued.
+func (q *queuedRender) rerender() DiffStats {
+	q.mu.Lock()
+	if q.draining {
+		q.mu.Unlock()
+		return DiffStats{}
+	}
+	q.draining = true
+	q.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			q.mu.Lock()
+			q.draining = false
+			q.mu.Unlock()
+			panic(r)
+		}
+	}()
+	q.v.stats = DiffStats{}
+	var stats DiffStats
+	for cmp := q.next(&stats); cmp != nil; cmp = q.next(&stats) {
 		if cmp.core().dirty {
-			q.v.renderComponent(cmp, 0, false, false)
+			q.v.rerenderComponent(cmp)
 		}
 	}
+	return stats
 }
 
 // CallbackGenerator is a function that returns callbacks.
@@ -462,41 +958,301 @@ type Vected struct {
 	// The registered components won't be used as is, instead new instance will be
 	// created so please don't pass component's which have state in them
 	// (initialized field values etc) here, because they will be ignored.
-	components map[string]Component
+	components map[string]*registration
 
 	// Is the browser's document object. New document elements will be created from
-	// this.
+	// this. This is set by WithBackend.
 	Document Element
 
 	// mounts is a list of components ready to be mounted.
 	mounts *list.List
 
-	isSVGMode bool
-	hydrating bool
-	diffLevel int
+	// holdMounts keeps diff from mounting the components in mounts, they are
+	// mounted when the tree rendered by RenderOffscreen is committed.
+	holdMounts bool
+
+	// roots are trees rendered with Render.
+	roots []mountedRoot
+
+	// released are nodes of trees replaced during the current render pass,
+	// they are returned to the node pool when the pass is complete.
+	released []*Node
+
+	// Dev enables checks that are too expensive or too strict for production,
+	// problems that are otherwise logged and worked around panic instead.
+	Dev bool
+
+	// Warn receives messages about problems that were logged and worked around,
+	// like server markup that doesn't match on hydration, a full render queue or
+	// a component rolled back after a panic. Messages are written with the
+	// standard logger when this is nil.
+	Warn func(msg string)
+
+	// SkipWhitespace removes whitespace only text nodes and comments found in
+	// the dom while hydrating children. Enable this when hydrating server
+	// rendered markup, which usually has whitespace that the virtual nodes lack.
+	// Nodes rendered on the client, like the comments of Empty nodes, are kept.
+	SkipWhitespace bool
+
+	// MinimalMoves reorders lists where every child is keyed by moving only the
+	// nodes that are not part of the longest increasing subsequence of their
+	// old positions. This keeps the number of dom moves minimal, for instance a
+	// reversed list of n children costs n-1 moves.
+	MinimalMoves bool
+
+	// PoolNodes puts the nodes of trees returned by Render methods back to the
+	// pool used by NewNode once a later render replaced them and the render
+	// pass is complete. Only nodes the component built itself are released,
+	// nodes it received through props and nodes passed on to child components
+	// are left alone.
+	//
+	// Only enable this when Render methods always build fresh trees with
+	// NewNode, keeping a *Node the component built around across renders while
+	// pooling is on leads to corrupted trees.
+	PoolNodes bool
+
+	// namespace is the namespace of the root of the current render.
+	namespace string
+
+	isSVGMode    bool
+	isMathMLMode bool
+	hydrating    bool
+	diffLevel    int
 
 	cache map[int]Component
 	refs  map[int]int
 
-	cb CallbackGenerator
+	// attrs are attributes applied to elements in the last render keyed by the
+	// id stored in the element's AttrKey.
+	attrs map[int][]Attribute
+
+	// portals are rendered portals keyed by the id stored in the AttrKey of the
+	// comment keeping their place.
+	portals map[int]*portal
+
+	// listeners are the event listeners added to elements keyed by the id
+	// stored in the element's AttrKey and the name of the attribute.
+	listeners map[int]map[string]listener
+
+	cb         CallbackGenerator
+	visibility VisibilityObserver
+	scheduler  func(func())
+
+	// owner is the component being rendered and childIndex the position of the
+	// node being diffed among its siblings, new components record them.
+	owner      Component
+	childIndex int
+
+	// Trace when set is called with every decision taken while diffing. Use it
+	// to understand why dom nodes are reused or recreated.
+	Trace func(TraceEvent)
+
+	// stats counts changes made by the current render pass.
+	stats DiffStats
+
+	// onError is called with panics raised while re rendering queued
+	// components, see OnError.
+	onError func(error, ComponentStack)
+
+	// drag is the drag and drop gesture in progress, see Draggable.
+	drag *dragSession
+}
+
+// SetQueueSize sets the maximum number of components waiting to be re
+// rendered, this guards memory against runaway update loops. A size of 0
+// disables the limit. The default is DefaultQueueSize.
+func (v *Vected) SetQueueSize(size int) {
+	v.queue.mu.Lock()
+	v.queue.size = size
+	v.queue.mu.Unlock()
+}
+
+// Backend is the environment Vected renders to.
+type Backend struct {
+	// Document creates new nodes, in the browser this is the document object.
+	Document Element
+
+	// Callbacks turns go functions into values that can be passed to the dom,
+	// like event listeners.
+	Callbacks CallbackGenerator
+
+	// Visibility is optional, it is used to hydrate islands when they become
+	// visible. In the browser this is implemented with IntersectionObserver.
+	Visibility VisibilityObserver
+
+	// Schedule is optional, it calls fn once before the next frame is painted
+	// and is used to render components queued by SetState. In the browser this
+	// is implemented with requestAnimationFrame. When missing fn is called from
+	// a timer after FrameDuration.
+	Schedule func(fn func())
+}
+
+// FrameDuration is the delay before rendering queued components when the
+// backend has no Schedule function.
+var FrameDuration = time.Second / 60
+
+// schedule calls fn with the scheduler of the backend.
+func (v *Vected) schedule(fn func()) {
+	if v.scheduler != nil {
+		v.scheduler(fn)
+		return
+	}
+	time.AfterFunc(FrameDuration, fn)
+}
+
+// VisibilityObserver calls fn once, when elem scrolls into view.
+type VisibilityObserver func(elem Element, fn func())
+
+// Option configures a Vected instance created with New.
+type Option func(*Vected)
+
+// WithBackend renders to b. This is how to pick between the wasm dom, an in
+// memory dom for tests or any other implementation of Element.
+func WithBackend(b Backend) Option {
+	return func(v *Vected) {
+		v.Document = b.Document
+		v.cb = b.Callbacks
+		v.visibility = b.Visibility
+		v.scheduler = b.Schedule
+	}
 }
 
 // New returns an initialized Vected instance.
-func New() *Vected {
+func New(opts ...Option) *Vected {
 	v := &Vected{
 		cache:      make(map[int]Component),
 		refs:       make(map[int]int),
+		attrs:      make(map[int][]Attribute),
+		portals:    make(map[int]*portal),
+		listeners:  make(map[int]map[string]listener),
 		mounts:     list.New(),
-		components: make(map[string]Component),
+		components: make(map[string]*registration),
+		childIndex: -1,
 	}
 	v.queue = newQueuedRender(v)
+	for _, opt := range opts {
+		opt(v)
+	}
 	return v
 }
 
-func (v *Vected) enqueueRender(cmp Component) {
-	if cmp.core().dirty {
-		v.queue.Push(cmp)
-		v.queue.Rerender()
+// Backend returns the backend v renders to.
+func (v *Vected) Backend() Backend {
+	return Backend{
+		Document:   v.Document,
+		Callbacks:  v.cb,
+		Visibility: v.visibility,
+		Schedule:   v.scheduler,
+	}
+}
+
+// Flush renders all components waiting in the render queue before returning.
+// Use it in tests instead of waiting for asynchronous renders triggered by
+// SetState.
+//
+// This returns the changes made to the dom by the flushed components. When
+// the queue is already being drained, for instance when Flush is called from
+// a lifecycle method, it returns right away with empty stats and the queue is
+// rendered by the running drain.
+func (v *Vected) Flush() DiffStats {
+	return v.queue.rerender()
+}
+
+// Stats returns the changes made to the dom by the last call to Render or
+// Flush.
+func (v *Vected) Stats() DiffStats {
+	return v.stats
+}
+
+// OnError sets fn to be called with errors raised while re rendering
+// components after a state change, when no ErrorBoundary caught them. Without
+// it the error is raised again as a panic, which crashes the program since
+// queued components are rendered in their own goroutine.
+//
+// In both cases the failed component is first rendered again with its last
+// good props and state, so the page keeps showing the last good tree. The
+// failed render isn't staged, it updates the dom until the panic and the
+// rollback renders over it. Children unmounted by the failed render have
+// received ComponentWillUnmount, the rollback mounts new instances in their
+// place with their initial state.
+func (v *Vected) OnError(fn func(err error, info ComponentStack)) {
+	v.onError = fn
+}
+
+// rerenderComponent renders queued cmp. A panic is passed to the OnError
+// handler, or raised again, after rolling back the update.
+func (v *Vected) rerenderComponent(cmp Component) {
+	e := v.catch(func() {
+		v.renderComponent(cmp, 0, false, false)
+	})
+	if e == nil {
+		return
+	}
+	v.rollback(cmp)
+	if v.onError == nil {
+		panic(e.err)
+	}
+	v.onError(e.err, e.stack)
+}
+
+// rollback renders cmp again with the props, state and context of its last
+// successful render. The dom may have been partially updated when rendering
+// failed, this brings back the last good tree, see OnError.
+func (v *Vected) rollback(cmp Component) {
+	core := cmp.core()
+	if core.base == nil || core.previousProps == nil {
+		return
+	}
+	core.props = core.previousProps
+	core.state = core.previousState
+	core.context = core.previousContext
+	core.prevProps, core.prevState, core.prevContext = nil, nil, nil
+	core.updaters = nil
+	if e := v.catch(func() {
+		v.renderComponent(cmp, Force, false, false)
+	}); e != nil {
+		v.warn(fmt.Sprintf("greact: rolling back %s: %v", core.Path(), e.err))
+	}
+}
+
+// Pause stops queued components from being re rendered, for instance during a
+// drag. Components whose state changes while paused stay in the queue, each is
+// rendered once by Resume.
+func (v *Vected) Pause() {
+	v.queue.mu.Lock()
+	v.queue.paused = true
+	v.queue.mu.Unlock()
+}
+
+// Resume undoes Pause and renders the components queued in the meantime before
+// returning.
+func (v *Vected) Resume() {
+	v.queue.mu.Lock()
+	v.queue.paused = false
+	v.queue.mu.Unlock()
+	v.queue.rerender()
+}
+
+// Batch calls fn and defers re rendering of components whose state changed
+// inside fn until it returns. Components are then rendered once, before Batch
+// returns, or by the running drain when Batch is called while the queue is
+// being rendered. Event handlers are always called inside Batch.
+func (v *Vected) Batch(fn func()) {
+	v.queue.batch(1)
+	defer func() {
+		if v.queue.batch(-1) == 0 {
+			v.queue.rerender()
+		}
+	}()
+	fn()
+}
+
+// batched returns the event handler fn wrapped in Batch.
+func (v *Vected) batched(fn func([]Value)) func([]Value) {
+	return func(args []Value) {
+		v.Batch(func() {
+			fn(args)
+		})
 	}
 }
 
@@ -511,6 +1267,52 @@ func (v *Vected) flushMounts() {
 	}
 }
 
+// releaseNodes returns nodes replaced during the render pass to the node pool.
+func (v *Vected) releaseNodes() {
+	seen := make(map[*Node]bool)
+	for _, n := range v.released {
+		if !seen[n] {
+			seen[n] = true
+			resetNode(n)
+		}
+	}
+	v.released = nil
+}
+
+// ownedNodes appends to o the nodes of the tree n that a component built in its
+// Render method. The walk stops at nodes in keep, which came with the
+// component's props, and at child component nodes since their children became
+// props of that component.
+func (v *Vected) ownedNodes(o []*Node, n *Node, keep map[*Node]bool) []*Node {
+	if n == nil || keep[n] || v.isHigherOrder(n) {
+		return o
+	}
+	o = append(o, n)
+	for _, ch := range n.Children {
+		o = v.ownedNodes(o, ch, keep)
+	}
+	return o
+}
+
+// propNodes returns the nodes passed in props, they belong to the tree of the
+// component's parent.
+func propNodes(props ...Props) map[*Node]bool {
+	m := make(map[*Node]bool)
+	for _, p := range props {
+		for _, val := range p {
+			switch e := val.(type) {
+			case *Node:
+				m[e] = true
+			case []*Node:
+				for _, n := range e {
+					m[n] = true
+				}
+			}
+		}
+	}
+	return m
+}
+
 func (v *Vected) recollectNodeTree(node Element, unmountOnly bool) {
 	cmp := v.findComponent(node)
 	if cmp != nil {
@@ -519,56 +1321,189 @@ func (v *Vected) recollectNodeTree(node Element, unmountOnly bool) {
 		if !unmountOnly || !Valid(node.Get(AttrKey)) {
 			RemoveNode(node)
 		}
+		v.releaseRef(node)
+		v.releaseThrottled(node)
+		v.releaseListeners(node)
+		v.releasePortal(node)
+		v.forgetAttrs(node)
 		v.removeChildren(node)
 	}
 }
 
-// UndefinedFunc is a function  that returns a javascript undefined value.
-type UndefinedFunc func() Value
-
-// Undefined is a work around to allow the library to work with/without wasm
-// support.
-//
-// TODO: find a better way to handle this.
-var Undefined UndefinedFunc
+// releaseRef calls the ref callback of elem, if it has one, with nil.
+func (v *Vected) releaseRef(elem Element) {
+	if attrs, ok := v.cachedAttrs(elem); ok {
+		for _, a := range attrs {
+			if fn, ok := a.Val.(func(interface{})); ok && a.Key == "ref" {
+				fn(nil)
+			}
+		}
+	}
+}
 
-func (v *Vected) diffAttributes(node Element, attrs, old []Attribute) {
+// diffAttributes applies attrs to node. old are the attributes that were
+// applied in the previous render, or the ones found in the dom when hydrating.
+// Only attributes whose values changed are written to the dom, this returns
+// true if any was.
+func (v *Vected) diffAttributes(node Element, attrs, old []Attribute) (changed bool) {
 	a := mapAtts(attrs)
 	b := mapAtts(old)
 	for k, val := range b {
 		if _, ok := a[k]; !ok {
-			setAccessor(v.cb, node, k, val, Undefined(), v.isSVGMode)
+			changed = true
+			if t, ok := val.Val.(*throttled); ok && t.state != nil {
+				t.state.stop()
+			}
+			if strings.HasPrefix(k, "on") {
+				v.removeListener(node, k)
+				continue
+			}
+			setAccessor(node, k, val.Val, nil, v.isSVGMode)
 		}
 	}
-	for k := range a {
+	for k, val := range a {
 		switch k {
-		case "children", "innerHTML":
+		case "children", "innerHTML", "key":
+			// keys are only used for reconciliation.
 			continue
 		default:
-			setAccessor(v.cb, node, k, b[k], a[k], v.isSVGMode)
+			prev, ok := b[k]
+			if ok && sameAttrValue(prev.Val, val.Val) {
+				continue
+			}
+			changed = true
+			if strings.HasPrefix(k, "on") {
+				switch e := val.Val.(type) {
+				case *throttled:
+					// throttled listeners batch the calls they let through.
+					v.addListener(node, k, e.bind(v, prev.Val))
+				case func([]Value):
+					v.addListener(node, k, v.batched(e))
+				default:
+					// If we don't supply the event call back it is the same as
+					// saying remove this event.
+					v.removeListener(node, k)
+				}
+				continue
+			}
+			setAccessor(node, k, prev.Val, val.Val, v.isSVGMode)
 		}
 	}
+	return changed
 }
 
 func mapAtts(attrs []Attribute) map[string]Attribute {
 	m := make(map[string]Attribute)
 	for _, v := range attrs {
+		if v.Key == "className" {
+			v.Key = "class"
+		}
 		m[v.Key] = v
 	}
 	return m
 }
 
+// sameAttrValue returns true if the attribute value a is the same as b. a can
+// be a string read from the dom, in which case it is compared to the string
+// form of b.
+func sameAttrValue(a, b interface{}) bool {
+	if s, ok := a.(string); ok {
+		switch e := b.(type) {
+		case string:
+			return s == e
+		case int, float64:
+			return s == fmt.Sprint(e)
+		default:
+			return false
+		}
+	}
+	if reflect.ValueOf(a).Kind() == reflect.Func {
+		// there is no way to tell if two functions are the same.
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// domAttributes returns attributes of elem as found in the dom.
+func domAttributes(elem Element) []Attribute {
+	a := elem.Get("attributes")
+	if !Valid(a) {
+		return nil
+	}
+	var o []Attribute
+	size := a.Get("length").Int()
+	for i := 0; i < size; i++ {
+		attr := a.Index(i)
+		o = append(o, Attribute{
+			Key: attr.Get("name").String(),
+			Val: attr.Get("value").String(),
+		})
+	}
+	return o
+}
+
+// cacheAttrs remembers the attributes applied to elem so the next render can
+// diff against them.
+func (v *Vected) cacheAttrs(elem Element, attrs []Attribute) {
+	v.attrs[elemID(elem)] = attrs
+}
+
+// elemID returns the id stored in the AttrKey of elem, a new one is stored if
+// elem has none.
+func elemID(elem Element) int {
+	id := elem.Get(AttrKey)
+	if id.Type() != TypeNumber {
+		n := idPool.Get().(int)
+		elem.Set(AttrKey, n)
+		return n
+	}
+	return id.Int()
+}
+
+// cachedAttrs returns the attributes applied to elem in the last render.
+func (v *Vected) cachedAttrs(elem Element) ([]Attribute, bool) {
+	id := elem.Get(AttrKey)
+	if id.Type() != TypeNumber {
+		return nil, false
+	}
+	a, ok := v.attrs[id.Int()]
+	return a, ok
+}
+
+func (v *Vected) forgetAttrs(elem Element) {
+	if id := elem.Get(AttrKey); id.Type() == TypeNumber {
+		delete(v.attrs, id.Int())
+	}
+}
+
 func (v *Vected) diff(ctx context.Context, elem Element, node *Node, parent Element, mountAll, componentRoot bool) Element {
 	if v.diffLevel == 0 {
-		v.diffLevel++
 		// when first starting the diff, check if we're diffing an SVG or within an SVG
-		v.isSVGMode = parent != nil && parent.Type() != TypeNull &&
-			Valid(parent.Get("ownerSVGElement"))
+		v.isSVGMode = v.namespace == SVGNamespace ||
+			(parent != nil && parent.Type() != TypeNull &&
+				Valid(parent.Get("ownerSVGElement")))
+		v.isMathMLMode = v.namespace == MathMLNamespace
 
 		// hydration is indicated by the existing element to be diffed not having a
 		// prop cache
-		v.hydrating = Valid(elem) && Valid(elem.Get(AttrKey))
+		v.hydrating = Valid(elem) && !Valid(elem.Get(AttrKey))
 	}
+	// lifecycle methods can render synchronously while we are diffing, the
+	// level keeps track of nested calls so that only the outermost diff flushes
+	// mounts.
+	v.diffLevel++
+	defer func() {
+		v.diffLevel--
+		if v.diffLevel == 0 {
+			v.hydrating = false
+			if !componentRoot {
+				if !v.holdMounts {
+					v.flushMounts()
+				}
+				v.releaseNodes()
+			}
+		}
+	}()
 	ret := v.idiff(ctx, elem, node, mountAll, componentRoot)
 
 	// append the element if its a new parent
@@ -576,30 +1511,49 @@ func (v *Vected) diff(ctx context.Context, elem Element, node *Node, parent Elem
 		!IsEqual(ret.Get("parentNode"), parent) {
 		parent.Call("appendChild", ret)
 	}
-	v.diffLevel--
-	if v.diffLevel == 0 {
-		v.hydrating = false
-		if !componentRoot {
-			v.flushMounts()
-		}
-	}
 	return ret
 }
 
 func (v *Vected) idiff(ctx context.Context, elem Element, node *Node, mountAll, componentRoot bool) Element {
+	if node == nil {
+		node = Empty()
+	}
 	out := elem
 	prevSVGMode := v.isSVGMode
+	prevMathMLMode := v.isMathMLMode
 	switch node.Type {
+	case CommentNode:
+		if Valid(elem) && isComment(elem) && v.portalOf(elem) == nil {
+			if elem.Get("nodeValue").String() != node.Data {
+				elem.Set("nodeValue", node.Data)
+				v.trace(TraceUpdateText, node, elem)
+			} else {
+				v.trace(TraceReuse, node, elem)
+			}
+		} else {
+			out = v.Document.Call("createComment", node.Data)
+			v.traceNew(node, out, elem)
+			if Valid(elem) {
+				if Valid(elem.Get("parentNode")) {
+					elem.Get("parentNode").Call("replaceChild", out, elem)
+				}
+				v.recollectNodeTree(elem, true)
+			}
+		}
+		out.Set(AttrKey, true)
+		return out
 	case TextNode:
 		if Valid(elem) && Valid(elem.Get("splitText")) &&
 			Valid(elem.Get("parentNode")) {
-			v := elem.Get("nodeValue").String()
-			if v != node.Data {
+			if elem.Get("nodeValue").String() != node.Data {
 				elem.Set("nodeValue", node.Data)
+				v.trace(TraceUpdateText, node, elem)
+			} else {
+				v.trace(TraceReuse, node, elem)
 			}
-
 		} else {
 			out = v.Document.Call("createTextNode", node.Data)
+			v.traceNew(node, out, elem)
 			if Valid(elem) {
 				if Valid(elem.Get("parentNode")) {
 					elem.Get("parentNode").Call("replaceChild", out, elem)
@@ -610,7 +1564,6 @@ func (v *Vected) idiff(ctx context.Context, elem Element, node *Node, mountAll,
 		out.Set(AttrKey, true)
 		return out
 	case ElementNode:
-		fmt.Printf("rendering %s\n", node.Data)
 		if v.isHigherOrder(node) {
 			return v.buildComponentFromVNode(ctx, elem, node, mountAll, false)
 		}
@@ -621,34 +1574,60 @@ func (v *Vected) idiff(ctx context.Context, elem Element, node *Node, mountAll,
 				v.isSVGMode = false
 			}
 		}
+		if node.Data == "math" {
+			v.isMathMLMode = true
+		} else if v.isMathMLMode && !elements.MathML(node.Data) {
+			// html content embedded in MathML
+			v.isMathMLMode = false
+		}
 		nodeName := node.Data
-		if !Valid(elem) || !isNamedNode(elem, node) {
-			out = v.CreateNode(nodeName)
+		mismatch := v.hydrating && Valid(elem) && isNamedNode(elem, node) &&
+			hydrationMismatch(elem, node)
+		if mismatch {
+			msg := fmt.Sprintf("greact: server markup of <%s> rendered by %s doesn't match, rendering it again",
+				nodeName, v.ownerPath())
+			if v.Dev {
+				panic(msg)
+			}
+			v.warn(msg)
+		}
+		reused := true
+		if !Valid(elem) || !isNamedNode(elem, node) || mismatch {
+			if v.isSVGMode {
+				out = v.CreateSVGNode(v.Document, nodeName)
+			} else if v.isMathMLMode {
+				out = v.CreateMathMLNode(nodeName)
+			} else {
+				out = v.CreateNode(nodeName)
+			}
+			v.traceNew(node, out, elem)
 			if Valid(elem) {
-				if Valid(elem.Get("firstChild")) {
-					out.Call("appendChild", elem.Get("firstChild"))
+				// move children to the replacement element so they can be reused,
+				// unless they are mismatched server markup.
+				for fc := elem.Get("firstChild"); Valid(fc) && !mismatch; fc = elem.Get("firstChild") {
+					out.Call("appendChild", fc)
 				}
 				if e := elem.Get("parentNode"); Valid(e) {
 					elem.Get("parentNode").Call("replaceChild", out, elem)
 				}
 				v.recollectNodeTree(elem, true)
 			}
+			reused = false
+		} else {
+			v.trace(TraceReuse, node, out)
 		}
 		fc := out.Get("firstChild")
-		props := out.Get(AttrKey)
-		var old []Attribute
-		if !Valid(props) {
-			a := out.Get("attributes")
-			if keys, err := Keys(a); err == nil {
-				for _, v := range keys {
-					old = append(old, Attribute{
-						Key: v,
-						Val: a.Get(v).String(),
-					})
-				}
-			}
+		old, ok := v.cachedAttrs(out)
+		if !ok {
+			// hydrating, adopt what is already in the dom.
+			old = domAttributes(out)
 		}
-		if !v.hydrating && len(node.Children) == 1 &&
+		if text, ok := editableText(node); ok && Valid(fc) &&
+			out.Get("textContent").String() == text {
+			// the content is what the user typed, touching it would reset the
+			// caret.
+			v.trace(TraceReuse, node, out)
+		} else if !v.hydrating && len(node.Children) == 1 &&
 			node.Children[0].Type == TextNode && Valid(fc) &&
 			Valid(fc.Get("splitText")) &&
 			fc.Get("nextSibling").Type() == TypeNull {
@@ -656,16 +1635,61 @@ func (v *Vected) idiff(ctx context.Context, elem Element, node *Node, mountAll,
 			fv := fc.Get("nodeValue").String()
 			if fv != nv {
 				fc.Set("nodeValue", nv)
+				v.trace(TraceUpdateText, node.Children[0], fc)
+			} else {
+				v.trace(TraceReuse, node.Children[0], fc)
 			}
 		} else if len(node.Children) > 0 || Valid(fc) {
 			v.innerDiffMode(ctx, out, node.Children, mountAll, v.hydrating)
 		}
-		v.diffAttributes(out, node.Attr, old)
+		if v.diffAttributes(out, node.Attr, old) && reused {
+			v.trace(TraceUpdateAttrs, node, out)
+		}
+		if node.Data == "select" {
+			for _, a := range node.Attr {
+				if a.Key == "value" && a.Val != nil {
+					// options exist only now, select the one matching value.
+					selectValue(out, fmt.Sprint(a.Val))
+				}
+			}
+		}
+		v.cacheAttrs(out, node.Attr)
+		if key := node.Key(); key != "" {
+			out.Set(keyKey, key)
+		}
 		v.isSVGMode = prevSVGMode
+		v.isMathMLMode = prevMathMLMode
 		return out
+	case PortalNode:
+		return v.diffPortal(ctx, elem, node, mountAll)
+	case FragmentNode:
+		return v.idiff(ctx, elem, fragmentRoot(node), mountAll, componentRoot)
 	default:
-		panic("Un supported node")
+		msg := fmt.Sprintf("greact: unsupported %s with data %q rendered by %s",
+			node.Type, node.Data, v.ownerPath())
+		if v.Dev {
+			panic(msg)
+		}
+		v.warn(msg)
+		return v.idiff(ctx, elem, Empty(), mountAll, componentRoot)
+	}
+}
+
+// warn reports msg to Warn, or to the standard logger.
+func (v *Vected) warn(msg string) {
+	if v.Warn != nil {
+		v.Warn(msg)
+		return
+	}
+	log.Print(msg)
+}
+
+// ownerPath returns the path of the component being rendered, for messages.
+func (v *Vected) ownerPath() string {
+	if v.owner != nil {
+		return v.owner.core().Path()
 	}
+	return "the root"
 }
 
 func (v *Vected) buildComponentFromVNode(ctx context.Context, elem Element, node *Node, mountAll, componentRoot bool) Element {
@@ -686,7 +1710,9 @@ func (v *Vected) buildComponentFromVNode(ctx context.Context, elem Element, node
 		break
 	}
 	if c != nil && isOwner && (!mountAll || c.core().component != nil) {
-		v.setProps(ctx, c, props, Async, mountAll)
+		// Like preact's default syncComponentUpdates, props coming from a parent
+		// render are applied synchronously.
+		v.setProps(ctx, c, props, Sync, mountAll)
 		elem = c.core().base
 	} else {
 		if originalComponent != nil && !isDirectOwner {
@@ -711,6 +1737,10 @@ func (v *Vected) buildComponentFromVNode(ctx context.Context, elem Element, node
 }
 
 func (v *Vected) innerDiffMode(ctx context.Context, elem Element, vchildrens []*Node, mountAll, isHydrating bool) {
+	if v.SkipWhitespace && isHydrating {
+		v.removeIgnorable(elem)
+	}
+	vchildrens = flatten(vchildrens)
 	original := elem.Get("childNodes")
 	length := original.Get("length").Int()
 	keys := make(map[string]Element)
@@ -719,16 +1749,15 @@ func (v *Vected) innerDiffMode(ctx context.Context, elem Element, vchildrens []*
 	if length > 0 {
 		for i := 0; i < length; i++ {
 			child := original.Index(i)
-			cmp := v.findComponent(child)
-			var key string
-			if cmp != nil {
-				key = cmp.core().key
-			}
+			key := v.childKey(child)
 			if key != "" {
 				keys[key] = child
 			} else {
 				var x bool
-				if cmp != nil || Valid(child.Get("splitText")) {
+				if Valid(child.Get(AttrKey)) {
+					// rendered by us
+					x = true
+				} else if Valid(child.Get("splitText")) {
 					v := child.Get("nodeValue").String()
 					v = strings.TrimSpace(v)
 					if isHydrating {
@@ -745,6 +1774,10 @@ func (v *Vected) innerDiffMode(ctx context.Context, elem Element, vchildrens []*
 			}
 		}
 	}
+	if v.MinimalMoves && len(children) == 0 && allKeyed(vchildrens) {
+		v.diffKeyed(ctx, elem, vchildrens, keys, mountAll)
+		return
+	}
 	for i := 0; i < len(vchildrens); i++ {
 		vchild := vchildrens[i]
 		key := vchild.Key()
@@ -767,9 +1800,12 @@ func (v *Vected) innerDiffMode(ctx context.Context, elem Element, vchildrens []*
 				}
 			}
 		}
-		child = v.idiff(ctx, child, vchild, mountAll, false)
+		child = v.diffChild(ctx, child, vchild, i, len(vchildrens), mountAll)
 		f := original.Index(i)
 		if Valid(child) && !IsEqual(child, elem) && !IsEqual(child, f) {
+			if IsEqual(child.Get("parentNode"), elem) {
+				v.stats.Moved++
+			}
 			if f.Type() == TypeNull || f.Type() == TypeUndefined {
 				elem.Call("appendChild", child)
 			} else if IsEqual(child, f.Get("nextSibling")) {
@@ -782,31 +1818,263 @@ func (v *Vected) innerDiffMode(ctx context.Context, elem Element, vchildrens []*
 
 	// removing unused keyed  children
 	for _, val := range keys {
+		v.trace(TraceRemove, nil, val)
 		v.recollectNodeTree(val, false)
 	}
 	for i := min; i < len(children); i++ {
 		ch := children[i]
 		if ch != nil {
+			v.trace(TraceRemove, nil, ch)
 			v.recollectNodeTree(ch, false)
 		}
 	}
 }
 
+// diffChild diffs the child at index i of n children, the index is recorded
+// by components created for the child.
+func (v *Vected) diffChild(ctx context.Context, elem Element, node *Node, i, n int, mountAll bool) Element {
+	prev := v.childIndex
+	v.childIndex = -1
+	if n > 1 {
+		v.childIndex = i
+	}
+	defer func() {
+		v.childIndex = prev
+	}()
+	return v.idiff(ctx, elem, node, mountAll, false)
+}
+
+// diffKeyed reconciles children of elem when every virtual child is keyed.
+// keys holds the existing keyed dom children of elem.
+//
+// The old position of every reused child is recorded, children whose old
+// positions form the longest increasing subsequence are already in the right
+// relative order and stay where they are, the rest are moved into place
+// walking backwards so every node can be inserted before its successor.
+func (v *Vected) diffKeyed(ctx context.Context, elem Element, vchildrens []*Node, keys map[string]Element, mountAll bool) {
+	position := make(map[string]int)
+	var i int
+	for child := elem.Get("firstChild"); Valid(child); child = child.Get("nextSibling") {
+		if k := v.childKey(child); k != "" {
+			if _, ok := keys[k]; ok {
+				position[k] = i
+				i++
+			}
+		}
+	}
+	out := make([]Element, len(vchildrens))
+	sources := make([]int, len(vchildrens))
+	for i, vchild := range vchildrens {
+		key := vchild.Key()
+		old, ok := keys[key]
+		if ok {
+			delete(keys, key)
+		}
+		child := v.diffChild(ctx, old, vchild, i, len(vchildrens), mountAll)
+		out[i] = child
+		sources[i] = -1
+		if ok && IsEqual(child, old) {
+			sources[i] = position[key]
+		}
+	}
+	for _, val := range keys {
+		v.trace(TraceRemove, nil, val)
+		v.recollectNodeTree(val, false)
+	}
+	stable := make(map[int]bool)
+	for _, i := range lis(sources) {
+		stable[i] = true
+	}
+	var next Element
+	for i := len(out) - 1; i >= 0; i-- {
+		child := out[i]
+		if !Valid(child) {
+			continue
+		}
+		if !stable[i] || !IsEqual(child.Get("parentNode"), elem) {
+			if IsEqual(child.Get("parentNode"), elem) {
+				v.stats.Moved++
+			}
+			if next == nil {
+				elem.Call("appendChild", child)
+			} else {
+				elem.Call("insertBefore", child, next)
+			}
+		}
+		next = child
+	}
+}
+
+// childKey returns the key of the dom node child.
+func (v *Vected) childKey(child Element) string {
+	if cmp := v.findComponent(child); cmp != nil {
+		return cmp.core().key
+	}
+	if k := child.Get(keyKey); Valid(k) {
+		return k.String()
+	}
+	return ""
+}
+
+func allKeyed(nodes []*Node) bool {
+	for _, n := range nodes {
+		if n.Key() == "" {
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+// lis returns indices of the longest strictly increasing subsequence of s.
+// Negative values are ignored.
+func lis(s []int) []int {
+	// tails[k] is the index in s of the smallest tail of all increasing
+	// subsequences of length k+1.
+	var tails []int
+	prev := make([]int, len(s))
+	for i, x := range s {
+		if x < 0 {
+			continue
+		}
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			m := (lo + hi) / 2
+			if s[tails[m]] < x {
+				lo = m + 1
+			} else {
+				hi = m
+			}
+		}
+		if lo > 0 {
+			prev[i] = tails[lo-1]
+		} else {
+			prev[i] = -1
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+	result := make([]int, len(tails))
+	for i, k := len(tails)-1, tails[len(tails)-1]; i >= 0; i-- {
+		result[i] = k
+		k = prev[k]
+	}
+	return result
+}
+
+// editableText returns the text of node when it is a contenteditable element
+// with only text children.
+func editableText(node *Node) (string, bool) {
+	editable := false
+	for _, a := range node.Attr {
+		if strings.ToLower(a.Key) == "contenteditable" {
+			editable = a.Val != false && a.Val != "false"
+		}
+	}
+	if !editable {
+		return "", false
+	}
+	var text strings.Builder
+	for _, ch := range node.Children {
+		if ch.Type != TextNode {
+			return "", false
+		}
+		text.WriteString(ch.Data)
+	}
+	return text.String(), true
+}
+
+// selectValue sets the value of the select element sel and marks the option
+// with that value, including options inside optgroup elements, as selected.
+func selectValue(sel Element, value string) {
+	sel.Set("value", value)
+	var walk func(Element)
+	walk = func(e Element) {
+		for ch := e.Get("firstChild"); Valid(ch); ch = ch.Get("nextSibling") {
+			switch strings.ToLower(ch.Get("nodeName").String()) {
+			case "option":
+				ch.Set("selected", optionValue(ch) == value)
+			case "optgroup":
+				walk(ch)
+			}
+		}
+	}
+	walk(sel)
+}
+
+// optionValue returns the value of the option element opt, which is its text
+// when there is no value attribute.
+func optionValue(opt Element) string {
+	if v := opt.Call("getAttribute", "value"); Valid(v) {
+		return v.String()
+	}
+	var text string
+	for ch := opt.Get("firstChild"); Valid(ch); ch = ch.Get("nextSibling") {
+		if ch.Get("nodeType").Int() == textNodeType {
+			text += ch.Get("nodeValue").String()
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// removeIgnorable removes whitespace only text nodes and comments that are
+// direct children of elem. Nodes with an AttrKey were rendered by v and are
+// left for the diff to recollect.
+func (v *Vected) removeIgnorable(elem Element) {
+	for child := elem.Get("firstChild"); Valid(child); {
+		next := child.Get("nextSibling")
+		if isIgnorable(child) && !Valid(child.Get(AttrKey)) {
+			RemoveNode(child)
+		}
+		child = next
+	}
+}
+
+// isIgnorable returns true if elem is a comment or a text node with only
+// whitespace.
+func isIgnorable(elem Element) bool {
+	switch elem.Get("nodeType").Int() {
+	case commentNodeType:
+		return true
+	case textNodeType:
+		return strings.TrimSpace(elem.Get("nodeValue").String()) == ""
+	default:
+		return false
+	}
+}
+
 // isSameNodeType compares elem to vnode and returns true if thy are of the same
 // type.
 //
-// There are only two types of nodes supported , TextNode and ElementNode.
+// There are only three types of nodes supported , TextNode, ElementNode and
+// CommentNode.
 func isSameNodeType(elem Element, vnode *Node, isHydrating bool) bool {
 	switch vnode.Type {
 	case TextNode:
 		return Valid(elem.Get("splitText"))
 	case ElementNode:
+		// the root of a component matches the component, not its element.
+		if c := elem.Get(componentConstructor); Valid(c) && c.String() != "" {
+			return c.String() == vnode.Data
+		}
 		return isNamedNode(elem, vnode)
+	case CommentNode, PortalNode:
+		return isComment(elem)
 	default:
 		return false
 	}
 }
 
+func isComment(elem Element) bool {
+	t := elem.Get("nodeType")
+	return Valid(t) && t.Int() == commentNodeType
+}
+
 // isNamedNode compares elem to vnode to see if elem was created from the
 // virtual node of the same type as vnode..
 func isNamedNode(elem Element, vnode *Node) bool {
@@ -815,16 +2083,114 @@ func isNamedNode(elem Element, vnode *Node) bool {
 		name := v.String()
 		return name == vnode.Data
 	}
+	// elements that were not created by us like server rendered markup.
+	v = elem.Get("nodeName")
+	if Valid(v) {
+		return strings.ToLower(v.String()) == vnode.Data
+	}
 	return false
 }
 
-// Render renders vected component.
+// Render renders vected component. The changes made to the dom are returned by
+// Stats.
 func (v *Vected) Render(vnode *Node, parent Element, merge ...Element) Element {
+	return v.RenderContext(context.Background(), vnode, parent, merge...)
+}
+
+// RenderContext is like Render but ctx is the context passed to components in
+// the tree. Rendering is synchronous, mounted components have had
+// ComponentDidMount called by the time this returns. Use Unmount to remove the
+// tree.
+func (v *Vected) RenderContext(ctx context.Context, vnode *Node, parent Element, merge ...Element) Element {
 	var elem Element
 	if len(merge) > 0 {
 		elem = merge[0]
 	}
-	return v.diff(context.Background(), elem, vnode, parent, false, false)
+	v.stats = DiffStats{}
+	out := v.diff(ctx, elem, vnode, parent, false, false)
+	if Valid(parent) {
+		v.addRoot(parent, out)
+	}
+	return out
+}
+
+// RenderNS is like Render but the root of vnode is created in the namespace
+// ns. Use SVGNamespace or MathMLNamespace to render a standalone svg or MathML
+// tree, this is only needed when parent isn't already inside an svg or math
+// element.
+func (v *Vected) RenderNS(ns string, vnode *Node, parent Element, merge ...Element) Element {
+	v.namespace = ns
+	defer func() {
+		v.namespace = ""
+	}()
+	return v.Render(vnode, parent, merge...)
+}
+
+// RenderOffscreen renders vnode into a detached element, so the page shows no
+// intermediate state while the tree is built. Calling commit puts the element
+// in target in one step, replacing and unmounting the tree previously rendered
+// into target, after which the tree can be updated with Render and removed with
+// Unmount like any other root.
+//
+// ComponentDidMount is called by commit, once the tree is in target.
+func (v *Vected) RenderOffscreen(vnode *Node) (elem Element, commit func(target Element)) {
+	v.holdMounts = true
+	elem = v.diff(context.Background(), nil, vnode, nil, false, false)
+	v.holdMounts = false
+	mounts := v.mounts
+	v.mounts = list.New()
+	return elem, func(target Element) {
+		var old Element
+		for _, r := range v.roots {
+			if IsEqual(r.parent, target) {
+				old = r.elem
+			}
+		}
+		switch {
+		case IsEqual(elem.Get("parentNode"), target):
+		case Valid(old) && !IsEqual(old, elem) && IsEqual(old.Get("parentNode"), target):
+			target.Call("replaceChild", elem, old)
+		default:
+			target.Call("appendChild", elem)
+		}
+		if Valid(old) && !IsEqual(old, elem) {
+			v.recollectNodeTree(old, false)
+		}
+		v.addRoot(target, elem)
+		v.mounts.PushBackList(mounts)
+		mounts.Init()
+		v.flushMounts()
+	}
+}
+
+// mountedRoot is a tree that was rendered into a parent element with Render.
+type mountedRoot struct {
+	parent, elem Element
+}
+
+func (v *Vected) addRoot(parent, elem Element) {
+	for i := range v.roots {
+		if IsEqual(v.roots[i].parent, parent) {
+			v.roots[i].elem = elem
+			return
+		}
+	}
+	v.roots = append(v.roots, mountedRoot{parent: parent, elem: elem})
+}
+
+// Unmount removes the tree that was rendered into target with Render. Lifecycle
+// methods of mounted components are called as the tree is removed.
+//
+// This returns false if nothing was rendered into target.
+func (v *Vected) Unmount(target Element) bool {
+	for i, r := range v.roots {
+		if IsEqual(r.parent, target) {
+			v.roots = append(v.roots[:i], v.roots[i+1:]...)
+			v.recollectNodeTree(r.elem, false)
+			return true
+		}
+	}
+	return false
 }
 
 // RenderComponent compiles component cmp and renders it.
@@ -847,15 +2213,78 @@ func (v *Vected) RenderComponent(cmp string, parent Element, merge ...Element) (
 // resolves or element names to lowercase.
 func (v *Vected) Register(name string, cmp Component) {
 	name = strings.ToLower(name)
+	v.register(&registration{name: name, proto: cmp})
+}
+
+func (v *Vected) register(r *registration) {
 	if v.components == nil {
-		v.components = make(map[string]Component)
+		v.components = make(map[string]*registration)
 	}
-	v.components[name] = cmp
+	v.components[r.name] = r
+}
+
+// RegisterAll registers all components in cmps. Names are validated first, an
+// error is returned and nothing is registered if any name is empty, is a html
+// element or is already registered.
+//
+// Values that implement Component are registered as is, other constructors are
+// used to create new instances.
+func (v *Vected) RegisterAll(cmps map[string]Constructor) error {
+	names := make([]string, 0, len(cmps))
+	for name := range cmps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		n := strings.ToLower(name)
+		switch {
+		case n == "":
+			return errors.New("greact: component name can't be empty")
+		case elements.Valid(n):
+			return fmt.Errorf("greact: component name %s is a html element", name)
+		case seen[n] || v.components[n] != nil:
+			return fmt.Errorf("greact: component %s is already registered", name)
+		}
+		seen[n] = true
+	}
+	for _, name := range names {
+		r := &registration{name: strings.ToLower(name)}
+		if cmp, ok := cmps[name].(Component); ok {
+			r.proto = cmp
+		} else {
+			r.c = cmps[name]
+		}
+		v.register(r)
+	}
+	return nil
+}
+
+// registration is a component registered under name.
+type registration struct {
+	name string
+
+	// proto is the registered Component, instances are created from it like
+	// newInstance does. c is set instead for constructors registered with
+	// RegisterAll that are not Components.
+	proto Component
+	c     Constructor
+}
+
+// instance returns a new instance of the registered component.
+func (r *registration) instance(props Props) Component {
+	var cmp Component
+	if r.c != nil {
+		cmp = r.c.New(props)
+	} else {
+		cmp = newInstance(r.proto, props)
+	}
+	cmp.core().constructor = r.name
+	return cmp
 }
 
 // CreateNode creates a dom element.
 func (v *Vected) CreateNode(name string) Element {
-	fmt.Printf("creating a new node %s\n", name)
 	node := v.Document.Call("createElement", name)
 	node.Set("normalizedNodeName", name)
 	return node
@@ -863,7 +2292,14 @@ func (v *Vected) CreateNode(name string) Element {
 
 // CreateSVGNode creates svg dom element.
 func (v *Vected) CreateSVGNode(doc Value, name string) Element {
-	node := v.Document.Call("createElementNS", svg, name)
+	node := v.Document.Call("createElementNS", SVGNamespace, name)
+	node.Set("normalizedNodeName", name)
+	return node
+}
+
+// CreateMathMLNode creates MathML dom element.
+func (v *Vected) CreateMathMLNode(name string) Element {
+	node := v.Document.Call("createElementNS", MathMLNamespace, name)
 	node.Set("normalizedNodeName", name)
 	return node
 }
@@ -871,19 +2307,28 @@ func (v *Vected) CreateSVGNode(doc Value, name string) Element {
 var xlink = regexp.MustCompile(`^xlink:?`)
 
 // setAccessor Set a named attribute on the given Node, with special behavior
-// for some names and event handlers. If `value` is `null`, the
-// attribute/handler will be removed.
+// for some names. If `value` is `null`, the attribute will be removed. Event
+// handlers are added by diffAttributes with addListener.
 // node An element to mutate
 //
-// name The name/key to set, such as an event or attribute name
+// name The name/key to set, such as an attribute name
 // old The last value that was set for this name/node pair
-// value An attribute value, such as a function to be used as an event handler
+// value An attribute value
 // isSVG Are we currently diffing inside an svg?
-func setAccessor(gen CallbackGenerator, node Element, name string, old, val interface{}, isSVG bool) {
+func setAccessor(node Element, name string, old, val interface{}, isSVG bool) {
 	if name == "className" {
 		name = "class"
 	}
 	switch name {
+	case "key":
+		// keys are only used for reconciliation.
+	case "ref":
+		if fn, ok := old.(func(interface{})); ok {
+			fn(nil)
+		}
+		if fn, ok := val.(func(interface{})); ok {
+			fn(node)
+		}
 	case "class":
 		v := val
 		if v == nil {
@@ -911,42 +2356,9 @@ func setAccessor(gen CallbackGenerator, node Element, name string, old, val inte
 		node.Set("innerHTML", val)
 	default:
 		switch {
-		case strings.HasPrefix(name, "on"):
-			useCapture := name != strings.TrimSuffix(name, "Capture")
-			name = eventName(name)
-			if ev, ok := val.(func([]Value)); ok {
-				cb := gen(ev)
-				if old == nil {
-					node.Call("addEventListener", name, cb, useCapture)
-					// To release resources allocated for the callback we keep track of of all
-					// callbacks added to this node.
-					//
-					// These can be later removed by calling the functions.
-					var release Resource
-					release = gen(func(args []Value) {
-						node.Call("removeEventListener", name, cb, useCapture)
-						cb.Release()
-						release.Release()
-					})
-					releaseList := node.Get("_listeners")
-					if releaseList.Type() == TypeUndefined {
-						node.Set("_listeners", make(map[string]interface{}))
-						releaseList = node.Get("_listeners")
-					}
-					releaseList.Set(name, release)
-				}
-			} else {
-				// If we don't supply the event call back it is the same as saying remove
-				// this event.
-				//
-				// We release the resources allocated for the event callback and free up the
-				// event reference by setting its value to undefined.
-				releaseList := node.Get("_listeners")
-				if Valid(releaseList) {
-					releaseList.Call(name)
-					releaseList.Set(name, "")
-				}
-			}
+		case isCustomElement(node) && isComplex(val):
+			// custom elements expect complex values as properties.
+			node.Set(name, jsValue(val))
 		case name != "list" && name != "type" && !isSVG && HasProperty(node, name):
 			func() {
 				defer recover()
@@ -959,6 +2371,8 @@ func setAccessor(gen CallbackGenerator, node Element, name string, old, val inte
 			if (val == nil || !toBool(val)) && name != "spellcheck" {
 				node.Call("removeAttribute", name)
 			}
+		case isStringBool(name) && isBool(val):
+			node.Call("setAttribute", name, strconv.FormatBool(val.(bool)))
 		default:
 			ns := isSVG && (name != xlink.ReplaceAllString(name, ""))
 			isFalse := func() bool {
@@ -998,6 +2412,109 @@ func validSVGValue(v reflect.Kind) bool {
 	}
 }
 
+// stringBoolAttrs are attributes which expect "true" or "false" as values
+// instead of being toggled by presence. All aria-* attributes behave the same
+// way.
+var stringBoolAttrs = map[string]bool{
+	"contenteditable": true,
+	"draggable":       true,
+	"spellcheck":      true,
+}
+
+// isStringBool returns true if the attribute name must be set to the string
+// "true" or "false" when given a bool value.
+func isStringBool(name string) bool {
+	return strings.HasPrefix(name, "aria-") || stringBoolAttrs[name]
+}
+
+// isCustomElement returns true if node is a custom element, their names must
+// contain a dash.
+func isCustomElement(node Element) bool {
+	name := node.Get("nodeName")
+	return Valid(name) && strings.Contains(name.String(), "-")
+}
+
+// isComplex returns true if v is a map, struct, slice, array or a pointer to
+// one.
+func isComplex(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	e := reflect.ValueOf(v)
+	if e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+	switch e.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsValue converts slices and arrays to []interface{} and maps with string
+// keys to map[string]interface{}, recursively. These are the forms that can be
+// turned into javascript arrays and objects. Pointers are followed, structs and
+// other maps are converted to objects through their json encoding, and are nil
+// when they can't be encoded. Other values are returned as is.
+func jsValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	e := reflect.ValueOf(v)
+	switch e.Kind() {
+	case reflect.Ptr:
+		if e.IsNil() {
+			return nil
+		}
+		return jsValue(e.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if e.Kind() == reflect.Slice && e.IsNil() {
+			return nil
+		}
+		a := make([]interface{}, e.Len())
+		for i := range a {
+			a[i] = jsValue(e.Index(i).Interface())
+		}
+		return a
+	case reflect.Map:
+		if e.IsNil() {
+			return nil
+		}
+		if e.Type().Key().Kind() != reflect.String {
+			return jsonValue(v)
+		}
+		m := make(map[string]interface{}, e.Len())
+		for _, k := range e.MapKeys() {
+			m[k.String()] = jsValue(e.MapIndex(k).Interface())
+		}
+		return m
+	case reflect.Struct:
+		return jsonValue(v)
+	default:
+		return v
+	}
+}
+
+// jsonValue returns v decoded from its json encoding, or nil if v can't be
+// encoded.
+func jsonValue(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var o interface{}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil
+	}
+	return o
+}
+
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
 func toBool(v interface{}) bool {
 	if v, ok := v.(bool); ok {
 		return v
@@ -4,7 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var _ Component = (*A)(nil)
@@ -87,8 +92,10 @@ func TestVected_Render(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(buf.String())
-	t.Error("yay")
+	expect := "<div>Hello,World</div>"
+	if buf.String() != expect {
+		t.Errorf("expected %s got %s", expect, buf.String())
+	}
 }
 
 func wrapPanic(fn func()) (err error) {
@@ -104,12 +111,12 @@ func wrapPanic(fn func()) (err error) {
 func TestSetAccessor(t *testing.T) {
 	t.Run("should set classname", func(ts *testing.T) {
 		e := newObject()
-		setAccessor(nil, e, "className", nil, "classa", false)
+		setAccessor(e, "className", nil, "classa", false)
 		v := e.Get("className").String()
 		if v != "classa" {
 			t.Error("expected className to be set")
 		}
-		setAccessor(nil, e, "class", nil, "classb", false)
+		setAccessor(e, "class", nil, "classb", false)
 		v = e.Get("className").String()
 		if v != "classb" {
 			t.Error("expected className to be set")
@@ -118,10 +125,1892 @@ func TestSetAccessor(t *testing.T) {
 	t.Run("should set style", func(ts *testing.T) {
 		text := "color:blue;"
 		e := newObject()
-		setAccessor(nil, e, "style", nil, text, false)
+		setAccessor(e, "style", nil, text, false)
 		v := e.Get("style").Get("cssText").String()
 		if v != text {
 			t.Error("expected style.cssText to be set")
 		}
 	})
+	t.Run("should stringify aria booleans", func(ts *testing.T) {
+		e := newObject()
+		setAccessor(e, "aria-expanded", nil, false, false)
+		v := e.Call("getAttribute", "aria-expanded")
+		if v.Type() != TypeString || v.String() != "false" {
+			ts.Errorf("expected aria-expanded to be false got %v", v)
+		}
+		setAccessor(e, "aria-expanded", false, true, false)
+		v = e.Call("getAttribute", "aria-expanded")
+		if v.String() != "true" {
+			ts.Errorf("expected aria-expanded to be true got %v", v)
+		}
+	})
+	t.Run("should set objects as properties of custom elements", func(ts *testing.T) {
+		e := newObject().Call("createElement", "my-el").(*object)
+		config := map[string]interface{}{"theme": "dark"}
+		setAccessor(e, "config", nil, config, false)
+		if e.Call("hasAttribute", "config").Bool() {
+			ts.Error("expected config not to be set as an attribute")
+		}
+		v := e.Get("config")
+		if v.Type() != TypeObject || !reflect.DeepEqual(v.(*object).value, config) {
+			ts.Errorf("expected config property to be set got %v", v)
+		}
+	})
+	t.Run("should set structs as objects on custom elements", func(ts *testing.T) {
+		e := newObject().Call("createElement", "my-el").(*object)
+		type point struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		}
+		setAccessor(e, "points", nil, []*point{{X: 1, Y: 2}}, false)
+		setAccessor(e, "counts", nil, map[int]string{1: "one"}, false)
+		expect := []interface{}{map[string]interface{}{"x": 1.0, "y": 2.0}}
+		if v := e.Get("points"); v.Type() != TypeObject || !reflect.DeepEqual(v.(*object).value, expect) {
+			ts.Errorf("expected points property to be %v got %v", expect, v)
+		}
+		counts := map[string]interface{}{"1": "one"}
+		if v := e.Get("counts"); v.Type() != TypeObject || !reflect.DeepEqual(v.(*object).value, counts) {
+			ts.Errorf("expected counts property to be %v got %v", counts, v)
+		}
+	})
+	t.Run("should set slices as properties of custom elements", func(ts *testing.T) {
+		e := newObject().Call("createElement", "my-el").(*object)
+		setAccessor(e, "items", nil, []string{"a", "b"}, false)
+		if e.Call("hasAttribute", "items").Bool() {
+			ts.Error("expected items not to be set as an attribute")
+		}
+		v := e.Get("items")
+		expect := []interface{}{"a", "b"}
+		if v.Type() != TypeObject || !reflect.DeepEqual(v.(*object).value, expect) {
+			ts.Errorf("expected items property to be %v got %v", expect, v)
+		}
+	})
+}
+
+func TestVected_SkipWhitespace(t *testing.T) {
+	doc := newObject()
+	el := newObject()
+	server := doc.Call("createElement", "div")
+	span := doc.Call("createElement", "span")
+	server.Call("appendChild", doc.Call("createTextNode", "\n  "))
+	server.Call("appendChild", span)
+	server.Call("appendChild", doc.Call("createComment", "server"))
+	server.Call("appendChild", doc.Call("createTextNode", "\n"))
+	el.Call("appendChild", server)
+
+	v := New()
+	v.Document = doc
+	v.SkipWhitespace = true
+	h := NewNode
+	out := v.Render(h(ElementNode, "", "div", nil, h(ElementNode, "", "span", nil)), el, server)
+	if !IsEqual(out, server) {
+		t.Error("expected the server div to be reused")
+	}
+	children := server.(*object).children
+	if len(children) != 1 {
+		t.Fatalf("expected 1 child got %d", len(children))
+	}
+	if !IsEqual(children[0], span) {
+		t.Error("expected the server span to be reused")
+	}
+
+	// comments rendered on the client are kept.
+	body := newObject()
+	node := func(text string) *Node {
+		return h(ElementNode, "", "div", nil,
+			Empty(),
+			Portal(body, h(TextNode, "", text, nil)),
+		)
+	}
+	div := v.Render(node("a"), el).(*object)
+	v.Render(node("b"), el, div)
+	v.Render(node("c"), el, div)
+	if n := len(div.children); n != 2 {
+		t.Errorf("expected the empty and portal comments to be kept got %d children", n)
+	}
+	if n := len(body.children); n != 1 || body.children[0].nodeValue != "c" {
+		t.Errorf("expected the portal content to be updated in place got %d children", n)
+	}
+	if n := len(v.portals); n != 1 {
+		t.Errorf("expected a single portal got %d", n)
+	}
+}
+
+type unmountSpy struct {
+	Core
+	unmounted bool
+}
+
+func (u *unmountSpy) Render(context.Context, Props, State) *Node {
+	return NewNode(ElementNode, "", "div", nil,
+		NewNode(TextNode, "", "modal", nil),
+	)
+}
+
+func (u *unmountSpy) ComponentWillUnmount() {
+	u.unmounted = true
+}
+
+func TestVected_Unmount(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("modal", &unmountSpy{})
+	modal := newObject()
+	other := newObject()
+	v.Render(NewNode(ElementNode, "", "modal", nil), modal)
+	v.Render(NewNode(ElementNode, "", "p", nil), other)
+	cmp := v.findComponent(modal.children[0]).(*unmountSpy)
+
+	if v.Unmount(newObject()) {
+		t.Error("expected nothing to be unmounted")
+	}
+	if !v.Unmount(modal) {
+		t.Fatal("expected modal to be unmounted")
+	}
+	if !cmp.unmounted {
+		t.Error("expected ComponentWillUnmount to be called")
+	}
+	if len(modal.children) != 0 {
+		t.Errorf("expected modal to be empty got %d children", len(modal.children))
+	}
+	if len(other.children) != 1 {
+		t.Error("expected other root to be left alone")
+	}
+	if n := len(v.attrs); n != 1 {
+		t.Errorf("expected the attributes of the unmounted tree to be forgotten got %d", n)
+	}
+	if v.Unmount(modal) {
+		t.Error("expected modal to be unmounted only once")
+	}
+}
+
+type keyedItem struct {
+	Core
+}
+
+func (k *keyedItem) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "li", nil,
+		NewNode(TextNode, "", props.String("name"), nil),
+	)
+}
+
+func TestVected_keyedComponents(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("item", &keyedItem{})
+	list := func(keys ...string) *Node {
+		var items []*Node
+		for _, k := range keys {
+			items = append(items, NewNode(ElementNode, "", "item", Attrs(
+				Attr("", "key", k),
+				Attr("", "name", k),
+			)))
+		}
+		return NewNode(ElementNode, "", "ul", nil, items...)
+	}
+	el := newObject()
+	ul := v.Render(list("a", "b", "c"), el).(*object)
+	instances := make(map[string]Component)
+	for _, ch := range ul.children {
+		cmp := v.findComponent(ch)
+		if cmp == nil {
+			t.Fatal("expected a component")
+		}
+		instances[cmp.core().key] = cmp
+	}
+	v.Render(list("c", "a", "b"), el, ul)
+	var order string
+	for _, ch := range ul.children {
+		cmp := v.findComponent(ch)
+		if cmp == nil {
+			t.Fatal("expected a component")
+		}
+		if instances[cmp.core().key] != cmp {
+			t.Errorf("expected instance for %s to be preserved", cmp.core().key)
+		}
+		order += ch.children[0].nodeValue
+	}
+	if order != "cab" {
+		t.Errorf("expected cab got %s", order)
+	}
+}
+
+func keyedList(keys ...string) *Node {
+	var items []*Node
+	for _, k := range keys {
+		items = append(items, NewNode(ElementNode, "", "li", Attrs(Attr("", "key", k)),
+			NewNode(TextNode, "", k, nil),
+		))
+	}
+	return NewNode(ElementNode, "", "ul", nil, items...)
+}
+
+func TestVected_MinimalMoves(t *testing.T) {
+	sample := []struct {
+		from, to []string
+	}{
+		{[]string{"a", "b", "c", "d", "e"}, []string{"e", "d", "c", "b", "a"}},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"b", "c", "d", "e", "a"}},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"a", "x", "c", "e", "b"}},
+		{[]string{"a", "b", "c"}, []string{"d", "e"}},
+		{[]string{"a"}, []string{"c", "b", "a"}},
+	}
+	for _, s := range sample {
+		v := New()
+		v.Document = newObject()
+		v.MinimalMoves = true
+		el := newObject()
+		ul := v.Render(keyedList(s.from...), el).(*object)
+		ids := make(map[string]int)
+		for _, ch := range ul.children {
+			ids[ch.children[0].nodeValue] = ch.id
+		}
+		v.Render(keyedList(s.to...), el, ul)
+		var got []string
+		for _, ch := range ul.children {
+			key := ch.children[0].nodeValue
+			got = append(got, key)
+			if id, ok := ids[key]; ok && id != ch.id {
+				t.Errorf("%v: expected element for %s to be reused", s.to, key)
+			}
+		}
+		if strings.Join(got, "") != strings.Join(s.to, "") {
+			t.Errorf("expected %v got %v", s.to, got)
+		}
+	}
+}
+
+func TestLIS(t *testing.T) {
+	sample := []struct {
+		src []int
+		lis []int
+	}{
+		{nil, nil},
+		{[]int{4, 3, 2, 1, 0}, []int{4}},
+		{[]int{0, 1, 2}, []int{0, 1, 2}},
+		{[]int{1, 2, 3, 4, 0}, []int{0, 1, 2, 3}},
+		{[]int{0, -1, 2, 4, 1}, []int{0, 2, 3}},
+	}
+	for _, s := range sample {
+		got := lis(s.src)
+		if fmt.Sprint(got) != fmt.Sprint(s.lis) {
+			t.Errorf("lis(%v): expected %v got %v", s.src, s.lis, got)
+		}
+	}
+}
+
+// countMoves returns the number of times children of o were moved or
+// inserted.
+func countMoves(o *object) int {
+	var n int
+	for _, j := range o.journal {
+		if j[0] == "call" {
+			switch j[1] {
+			case "appendChild", "insertBefore", "removeChild":
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func benchmarkReverse(b *testing.B, minimal bool) {
+	var keys, reversed []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, strconv.Itoa(i))
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		reversed = append(reversed, keys[i])
+	}
+	var moves int
+	for i := 0; i < b.N; i++ {
+		v := New()
+		v.Document = newObject()
+		v.MinimalMoves = minimal
+		el := newObject()
+		ul := v.Render(keyedList(keys...), el).(*object)
+		ul.journal = nil
+		v.Render(keyedList(reversed...), el, ul)
+		moves += countMoves(ul)
+	}
+	b.Logf("%.0f moves/op", float64(moves)/float64(b.N))
+}
+
+func BenchmarkReverse(b *testing.B) {
+	b.Run("naive", func(b *testing.B) {
+		benchmarkReverse(b, false)
+	})
+	b.Run("minimal", func(b *testing.B) {
+		benchmarkReverse(b, true)
+	})
+}
+
+type nothing struct {
+	Core
+}
+
+func (*nothing) Render(context.Context, Props, State) *Node {
+	return nil
+}
+
+func TestVected_renderNil(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("nothing", &nothing{})
+	node := NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "nothing", nil),
+		NewNode(ElementNode, "", "p", nil),
+	)
+	el := newObject()
+	err := wrapPanic(func() {
+		out := v.Render(node, el)
+		v.Render(node, el, out)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := renderObject(&buf, el.children[0]); err != nil {
+		t.Fatal(err)
+	}
+	expect := "<div><!----><p></p></div>"
+	if buf.String() != expect {
+		t.Errorf("expected %s got %s", expect, buf.String())
+	}
+}
+
+func TestVected_renderEmpty(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	h := NewNode
+	node := func(show bool) *Node {
+		child := Empty()
+		if show {
+			child = h(ElementNode, "", "p", nil, h(TextNode, "", "content", nil))
+		}
+		return h(ElementNode, "", "div", nil,
+			child,
+			h(ElementNode, "", "span", nil),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(true), el).(*object)
+	span := div.children[1]
+	for _, show := range []bool{false, true, false} {
+		v.Render(node(show), el, div)
+		if len(div.children) != 2 {
+			t.Fatalf("expected 2 children got %d", len(div.children))
+		}
+		if div.children[0].comment == show {
+			t.Errorf("expected placeholder to be rendered only when hidden")
+		}
+		if !IsEqual(div.children[1], span) {
+			t.Error("expected sibling to be preserved")
+		}
+	}
+}
+
+func TestVected_textElementTransition(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	h := NewNode
+	node := func(element bool) *Node {
+		child := h(TextNode, "", "hello", nil)
+		if element {
+			child = h(ElementNode, "", "span", nil, child)
+		}
+		return h(ElementNode, "", "div", nil, child)
+	}
+	el := newObject()
+	div := v.Render(node(false), el).(*object)
+	for _, element := range []bool{true, false, true} {
+		v.Render(node(element), el, div)
+		if len(div.children) != 1 {
+			t.Fatalf("expected 1 child got %d", len(div.children))
+		}
+		var buf bytes.Buffer
+		if err := renderObject(&buf, div); err != nil {
+			t.Fatal(err)
+		}
+		expect := "<div>hello</div>"
+		if element {
+			expect = "<div><span>hello</span></div>"
+		}
+		if buf.String() != expect {
+			t.Errorf("expected %s got %s", expect, buf.String())
+		}
+	}
+}
+
+func TestVected_hydrateAttributes(t *testing.T) {
+	doc := newObject()
+	el := newObject()
+	server := doc.Call("createElement", "div").(*object)
+	server.Call("setAttribute", "id", "main")
+	server.Call("setAttribute", "class", "box")
+	server.Call("setAttribute", "tabindex", "1")
+	el.Call("appendChild", server)
+	server.journal = nil
+
+	v := New()
+	v.Document = doc
+	node := NewNode(ElementNode, "", "div", Attrs(
+		Attr("", "id", "main"),
+		Attr("", "className", "box"),
+		Attr("", "tabindex", 1),
+	))
+	v.Render(node, el, server)
+	for _, step := range server.journal {
+		switch {
+		case step[0] == "call" && step[1] == "setAttribute",
+			step[0] == "call" && step[1] == "removeAttribute",
+			step[0] == "set" && step[1] == "className":
+			t.Errorf("expected no attribute writes got %v", step)
+		}
+	}
+	if server.attrs["id"] != "main" || server.attrs["class"] != "box" {
+		t.Errorf("expected server attributes to be kept got %v", server.attrs)
+	}
+}
+
+func TestVected_RenderNS(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	h := NewNode
+	node := h(ElementNode, "", "g", nil,
+		h(ElementNode, "", "circle", Attrs(Attr("", "r", 5))),
+	)
+	el := newObject()
+	g := v.RenderNS(SVGNamespace, node, el).(*object)
+	if g.namespace != SVGNamespace {
+		t.Errorf("expected root to be in %s got %q", SVGNamespace, g.namespace)
+	}
+	if g.children[0].namespace != SVGNamespace {
+		t.Errorf("expected child to be in %s got %q", SVGNamespace, g.children[0].namespace)
+	}
+	p := v.Render(h(ElementNode, "", "p", nil), newObject()).(*object)
+	if p.namespace != "" {
+		t.Errorf("expected html element got namespace %q", p.namespace)
+	}
+}
+
+func TestVected_MathML(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	h := NewNode
+	node := h(ElementNode, "", "p", nil,
+		h(ElementNode, "", "math", nil,
+			h(ElementNode, "", "mi", nil, h(TextNode, "", "x", nil)),
+		),
+	)
+	p := v.Render(node, newObject()).(*object)
+	if p.namespace != "" {
+		t.Errorf("expected html element got namespace %q", p.namespace)
+	}
+	math := p.children[0]
+	if math.namespace != MathMLNamespace {
+		t.Errorf("expected math to be in %s got %q", MathMLNamespace, math.namespace)
+	}
+	if mi := math.children[0]; mi.namespace != MathMLNamespace {
+		t.Errorf("expected mi to be in %s got %q", MathMLNamespace, mi.namespace)
+	}
+}
+
+type greeter struct {
+	Core
+}
+
+func (g *greeter) ComponentWillMount() {
+	g.SetState(State{"name": "gopher"})
+}
+
+func (g *greeter) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil,
+		NewNode(TextNode, "", "hello "+state.String("name"), nil),
+	)
+}
+
+func TestVected_setStateBeforeMount(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("greeter", &greeter{})
+	el := newObject()
+	p := v.Render(NewNode(ElementNode, "", "greeter", nil), el).(*object)
+	if got := p.children[0].nodeValue; got != "hello gopher" {
+		t.Errorf("expected hello gopher got %q", got)
+	}
+	if v.queue.Last() != nil {
+		t.Error("expected no render to be enqueued before mount")
+	}
+}
+
+type mountingRecorder struct {
+	Core
+	phases []bool
+}
+
+func (m *mountingRecorder) Render(ctx context.Context, props Props, state State) *Node {
+	m.phases = append(m.phases, m.IsMounting())
+	return NewNode(ElementNode, "", "p", nil)
+}
+
+func TestCore_IsMounting(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("recorder", &mountingRecorder{})
+	el := newObject()
+	node := func() *Node {
+		return NewNode(ElementNode, "", "recorder", nil)
+	}
+	p := v.Render(node(), el).(*object)
+	v.Render(node(), el, p)
+	cmp := v.findComponent(p).(*mountingRecorder)
+	if fmt.Sprint(cmp.phases) != "[true false]" {
+		t.Errorf("expected [true false] got %v", cmp.phases)
+	}
+}
+
+type prevRecorder struct {
+	Core
+	prevProps Props
+}
+
+func (p *prevRecorder) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil)
+}
+
+func (p *prevRecorder) ComponentDidUpdate(prevProps Props, prevState State) {
+	p.prevProps = p.PrevProps()
+}
+
+func TestCore_Prev(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("recorder", &prevRecorder{})
+	el := newObject()
+	node := func(name string) *Node {
+		return NewNode(ElementNode, "", "recorder", Attrs(Attr("", "name", name)))
+	}
+	p := v.Render(node("first"), el).(*object)
+	cmp := v.findComponent(p).(*prevRecorder)
+	if cmp.PrevProps() != nil || cmp.PrevState() != nil || cmp.PrevContext() != nil {
+		t.Error("expected no previous values before an update")
+	}
+	v.Render(node("second"), el, p)
+	if got := cmp.prevProps.String("name"); got != "first" {
+		t.Errorf("expected previous name to be first got %q", got)
+	}
+	if cmp.PrevContext() == nil {
+		t.Error("expected previous context")
+	}
+}
+
+type counter struct {
+	Core
+}
+
+func (c *counter) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil,
+		NewNode(TextNode, "", fmt.Sprint(state["count"]), nil),
+	)
+}
+
+func TestCore_SetStateMode(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	el := newObject()
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), el).(*object)
+	cmp := v.findComponent(p)
+	cmp.core().SetStateMode(Sync, State{"count": 1})
+	if got := p.children[0].nodeValue; got != "1" {
+		t.Errorf("expected 1 got %q", got)
+	}
+	cmp.core().SetStateMode(No, State{"count": 2})
+	if got := p.children[0].nodeValue; got != "1" {
+		t.Errorf("expected the dom to be left alone got %q", got)
+	}
+}
+
+func TestQueuedRender_bounded(t *testing.T) {
+	v := New()
+	v.SetQueueSize(4)
+	var warnings int
+	v.Warn = func(string) {
+		warnings++
+	}
+	for i := 0; i < 10; i++ {
+		c := &counter{}
+		c.id = i + 1
+		v.queue.Push(c)
+	}
+	if n := v.queue.Len(); n != 4 {
+		t.Errorf("expected 4 queued components got %d", n)
+	}
+	if warnings != 6 {
+		t.Errorf("expected a warning for each dropped component got %d", warnings)
+	}
+	cmp := v.queue.Pop()
+	v.queue.Push(cmp)
+	v.queue.Push(cmp)
+	if n := v.queue.Len(); n != 4 {
+		t.Errorf("expected duplicates to be ignored got %d queued", n)
+	}
+}
+
+type namedCounter struct {
+	counter
+}
+
+func (*namedCounter) DisplayName() string {
+	return "Counter"
+}
+
+func TestName(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	v.Register("named", &namedCounter{})
+	el := newObject()
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), el).(*object)
+	if got := Name(v.findComponent(p)); got != "counter" {
+		t.Errorf("expected counter got %q", got)
+	}
+	p = v.Render(NewNode(ElementNode, "", "named", nil), newObject()).(*object)
+	if got := Name(v.findComponent(p)); got != "Counter" {
+		t.Errorf("expected Counter got %q", got)
+	}
+}
+
+type form struct {
+	Core
+}
+
+func (f *form) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "form", nil,
+		NewNode(ElementNode, "", "input", Attrs(Attr("", "ref", f.Ref("name")))),
+		NewNode(ElementNode, "", "button", Attrs(Attr("", "ref", f.Ref("submit")))),
+	)
+}
+
+func TestCore_Ref(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("login", &form{})
+	el := newObject()
+	f := v.Render(NewNode(ElementNode, "", "login", nil), el).(*object)
+	cmp := v.findComponent(f).core()
+	for i, name := range []string{"name", "submit"} {
+		ref, ok := cmp.RefOf(name).(*object)
+		if !ok {
+			t.Fatalf("expected ref %s to be set", name)
+		}
+		if ref.id != f.children[i].id {
+			t.Errorf("expected ref %s to be %s got %s", name, f.children[i].name, ref.name)
+		}
+	}
+	v.Unmount(el)
+	if cmp.RefOf("name") != nil {
+		t.Error("expected ref to be cleared after unmount")
+	}
+}
+
+type release struct{}
+
+func (release) Release() {}
+
+type renderCounter struct {
+	Core
+	renders int
+}
+
+func (r *renderCounter) Render(ctx context.Context, props Props, state State) *Node {
+	r.renders++
+	return NewNode(ElementNode, "", "span", nil,
+		NewNode(TextNode, "", fmt.Sprint(state["count"]), nil),
+	)
+}
+
+func TestVected_batchEvents(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	var handlers []func([]Value)
+	v.cb = func(fn func([]Value)) Resource {
+		handlers = append(handlers, fn)
+		return release{}
+	}
+	v.Register("count", &renderCounter{})
+	var counters []*renderCounter
+	click := func([]Value) {
+		for _, c := range counters {
+			c.SetState(State{"count": 1})
+		}
+	}
+	h := NewNode
+	el := newObject()
+	div := v.Render(h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "button", Attrs(Attr("", "onClick", click))),
+		h(ElementNode, "", "count", nil),
+		h(ElementNode, "", "count", nil),
+		h(ElementNode, "", "count", nil),
+	), el).(*object)
+	for _, ch := range div.children[1:] {
+		counters = append(counters, v.findComponent(ch).(*renderCounter))
+	}
+	// the first callback is the click listener, the second releases it.
+	handlers[0](nil)
+	for i, c := range counters {
+		if c.renders != 2 {
+			t.Errorf("expected counter %d to render twice got %d", i, c.renders)
+		}
+		if got := div.children[i+1].children[0].nodeValue; got != "1" {
+			t.Errorf("expected counter %d to show 1 got %q", i, got)
+		}
+	}
+}
+
+func TestIDPool_concurrent(t *testing.T) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[int]bool)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := New()
+			for j := 0; j < 100; j++ {
+				id := v.createComponent(context.Background(), &counter{}, Props{}).core().id
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("id %d was issued twice", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type memoized struct {
+	renderCounter
+}
+
+func (m *memoized) ComponentWillMount() {
+	m.Memoize(true)
+}
+
+func TestCore_Memoize(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("memo", &memoized{})
+	el := newObject()
+	node := func(count int) *Node {
+		return NewNode(ElementNode, "", "memo", Attrs(Attr("", "count", count)))
+	}
+	span := v.Render(node(1), el).(*object)
+	cmp := v.findComponent(span).(*memoized)
+	v.Render(node(1), el, span)
+	if cmp.renders != 1 {
+		t.Errorf("expected a single render got %d", cmp.renders)
+	}
+	if got := span.children[0].nodeValue; got != "<nil>" {
+		t.Errorf("expected <nil> got %q", got)
+	}
+	cmp.SetStateMode(Sync, State{"count": 2})
+	if cmp.renders != 2 {
+		t.Errorf("expected state change to render got %d renders", cmp.renders)
+	}
+	if got := span.children[0].nodeValue; got != "2" {
+		t.Errorf("expected 2 got %q", got)
+	}
+	v.Render(node(2), el, span)
+	if cmp.renders != 3 {
+		t.Errorf("expected props change to render got %d renders", cmp.renders)
+	}
+}
+
+type toggleRoot struct {
+	Core
+}
+
+func (r *toggleRoot) Render(ctx context.Context, props Props, state State) *Node {
+	name := "div"
+	if state["span"] == true {
+		name = "span"
+	}
+	return NewNode(ElementNode, "", name, Attrs(Attr("", "id", "root")),
+		NewNode(ElementNode, "", "p", Attrs(Attr("", "id", "child"))),
+	)
+}
+
+func TestVected_rootTypeChange(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("toggle", &toggleRoot{})
+	el := newObject()
+	div := v.Render(NewNode(ElementNode, "", "toggle", nil), el).(*object)
+	cmp := v.findComponent(div)
+	child := div.children[0]
+	cmp.core().SetStateMode(Sync, State{"span": true})
+	if len(el.children) != 1 {
+		t.Fatalf("expected a single child got %d", len(el.children))
+	}
+	span := el.children[0]
+	if span.name != "span" {
+		t.Errorf("expected span got %s", span.name)
+	}
+	if !IsEqual(cmp.core().base, span) {
+		t.Error("expected span to be the component base")
+	}
+	if v.findComponent(span) != cmp {
+		t.Error("expected span to reference the component")
+	}
+	if div.parent != nil {
+		t.Error("expected the old root to be removed")
+	}
+	if v.findComponent(div) != nil {
+		t.Error("expected the old root to drop the component reference")
+	}
+	if _, ok := v.cachedAttrs(div); ok {
+		t.Error("expected attributes of the old root to be recollected")
+	}
+	// children are moved to the new root
+	if len(span.children) != 1 || span.children[0] != child {
+		t.Error("expected children of the old root to be reused")
+	}
+}
+
+// counterWrapper renders a counter, wrapped in a fragment when the frag prop
+// is true.
+type counterWrapper struct {
+	Core
+}
+
+func (w *counterWrapper) Render(ctx context.Context, props Props, state State) *Node {
+	child := NewNode(ElementNode, "", "counter", Attrs(Attr("", "count", props["count"])))
+	if props["frag"] == true {
+		return Frag(child)
+	}
+	return child
+}
+
+func TestVected_childComponentReuse(t *testing.T) {
+	for _, frag := range []bool{false, true} {
+		v := New()
+		v.Document = newObject()
+		v.Register("wrapper", &counterWrapper{})
+		v.Register("counter", &renderCounter{})
+		node := func(count int) *Node {
+			return NewNode(ElementNode, "", "wrapper", Attrs(
+				Attr("", "count", count),
+				Attr("", "frag", frag),
+			))
+		}
+		el := newObject()
+		span := v.Render(node(1), el).(*object)
+		child, ok := v.findComponent(span).core().component.(*renderCounter)
+		if !ok {
+			t.Fatalf("frag=%v: expected the wrapper to render a counter", frag)
+		}
+		if child.core().component != nil {
+			t.Errorf("frag=%v: expected the child not to be its own child component", frag)
+		}
+		v.Render(node(2), el, span)
+		if got := v.findComponent(span).core().component; got != Component(child) {
+			t.Errorf("frag=%v: expected the unkeyed child to be reused", frag)
+		}
+		if child.renders != 2 || child.props["count"] != 2 {
+			t.Errorf("frag=%v: expected the child to be updated got %d renders", frag, child.renders)
+		}
+	}
+}
+
+func TestIsSameNodeType_componentRoot(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &renderCounter{})
+	el := newObject()
+	div := v.Render(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "counter", nil),
+	), el).(*object)
+	span := div.children[0]
+	if !isSameNodeType(span, NewNode(ElementNode, "", "counter", nil), false) {
+		t.Error("expected the root of a component to match the component")
+	}
+	if isSameNodeType(span, NewNode(ElementNode, "", "span", nil), false) {
+		t.Error("expected the root of a component not to match its tag")
+	}
+	cmp := v.findComponent(span)
+	v.Render(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "counter", Attrs(Attr("", "count", 1))),
+	), el, div)
+	if v.findComponent(div.children[0]) != cmp {
+		t.Error("expected the component to be reused among unkeyed children")
+	}
+}
+
+func TestStringComponent(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("hello", StringComponent(func(context.Context, Props, State) string {
+		return "hello"
+	}))
+	el := newObject()
+	div := v.Render(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "hello", nil),
+	), el).(*object)
+	if len(div.children) != 1 {
+		t.Fatalf("expected a single child got %d", len(div.children))
+	}
+	text := div.children[0]
+	if text.Get("nodeType").Int() != textNodeType || text.nodeValue != "hello" {
+		t.Errorf("expected text node hello got %s %q", text.name, text.nodeValue)
+	}
+}
+
+type mountCounter struct {
+	renderCounter
+	mounts int
+}
+
+func (m *mountCounter) ComponentDidMount() {
+	m.mounts++
+}
+
+type reentrant struct {
+	mountCounter
+	host *Core
+}
+
+func (r *reentrant) New(Props) Component {
+	return &reentrant{host: r.host}
+}
+
+func (r *reentrant) ComponentWillMount() {
+	r.host.ForceUpdate()
+}
+
+func TestVected_reentrantRender(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("host", &renderCounter{})
+	v.Register("counter", &mountCounter{})
+	hostEl := v.Render(NewNode(ElementNode, "", "host", nil), newObject()).(*object)
+	host := v.findComponent(hostEl).(*renderCounter)
+	v.Register("trigger", &reentrant{host: host.core()})
+
+	el := newObject()
+	div := v.Render(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "trigger", nil),
+	), el).(*object)
+	if host.renders != 2 {
+		t.Errorf("expected host to be forced to render got %d renders", host.renders)
+	}
+	r := v.findComponent(div.children[0]).(*reentrant)
+	if r.mounts != 1 {
+		t.Errorf("expected trigger to be mounted once got %d", r.mounts)
+	}
+	if v.diffLevel != 0 {
+		t.Errorf("expected diff level to be 0 got %d", v.diffLevel)
+	}
+	c := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	if m := v.findComponent(c).(*mountCounter); m.mounts != 1 {
+		t.Errorf("expected later renders to flush mounts got %d", m.mounts)
+	}
+}
+
+type pathApp struct {
+	Core
+}
+
+func (*pathApp) Render(context.Context, Props, State) *Node {
+	return NewNode(ElementNode, "", "list", nil)
+}
+
+type pathList struct {
+	Core
+}
+
+func (*pathList) Render(context.Context, Props, State) *Node {
+	return NewNode(ElementNode, "", "ul", nil,
+		NewNode(ElementNode, "", "item", nil),
+		NewNode(ElementNode, "", "item", nil),
+		NewNode(ElementNode, "", "div", nil,
+			NewNode(ElementNode, "", "item", nil),
+		),
+	)
+}
+
+type pathItem struct {
+	Core
+}
+
+func (*pathItem) Render(context.Context, Props, State) *Node {
+	return NewNode(ElementNode, "", "li", nil)
+}
+
+func TestCore_Path(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("app", &pathApp{})
+	v.Register("list", &pathList{})
+	v.Register("item", &pathItem{})
+	ul := v.Render(NewNode(ElementNode, "", "app", nil), newObject()).(*object)
+	sample := []struct {
+		node   *object
+		expect string
+	}{
+		{ul.children[1], "app > list > item[1]"},
+		{ul.children[2].children[0], "app > list > item"},
+	}
+	for _, s := range sample {
+		if got := v.findComponent(s.node).core().Path(); got != s.expect {
+			t.Errorf("expected %s got %s", s.expect, got)
+		}
+	}
+}
+
+func TestVected_unsupportedNode(t *testing.T) {
+	node := func() *Node {
+		return NewNode(ElementNode, "", "div", nil,
+			NewNode(DoctypeNode, "", "html", nil),
+			NewNode(ElementNode, "", "p", nil),
+		)
+	}
+	v := New()
+	v.Document = newObject()
+	div := v.Render(node(), newObject()).(*object)
+	if len(div.children) != 2 || !isComment(div.children[0]) {
+		t.Errorf("expected a comment placeholder got %v", div.children)
+	}
+	v = New()
+	v.Document = newObject()
+	v.Dev = true
+	err := wrapPanic(func() {
+		v.Render(node(), newObject())
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported DoctypeNode") {
+		t.Errorf("expected a panic in dev mode got %v", err)
+	}
+}
+
+type itemConstructor struct{}
+
+func (itemConstructor) New(Props) Component {
+	return &pathItem{}
+}
+
+func TestVected_RegisterAll(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	err := v.RegisterAll(map[string]Constructor{
+		"counter": &reentrant{},
+		"item":    itemConstructor{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	li := v.Render(NewNode(ElementNode, "", "item", nil), newObject()).(*object)
+	if li.name != "li" {
+		t.Errorf("expected li got %s", li.name)
+	}
+	if _, ok := v.findComponent(li).(*pathItem); !ok {
+		t.Error("expected the constructor to create the component")
+	}
+
+	err = v.RegisterAll(map[string]Constructor{
+		"list":   &reentrant{},
+		"button": itemConstructor{},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a html element name")
+	}
+	if _, ok := v.components["list"]; ok {
+		t.Error("expected the batch to be rolled back")
+	}
+	err = v.RegisterAll(map[string]Constructor{
+		"Item": itemConstructor{},
+	})
+	if err == nil {
+		t.Error("expected an error for a duplicate name")
+	}
+}
+
+func TestVected_Register_twice(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	proto := &counter{}
+	v.Register("first", proto)
+	v.Register("second", proto)
+	for _, name := range []string{"first", "second"} {
+		p := v.Render(NewNode(ElementNode, "", name, nil), newObject())
+		if got := Name(v.findComponent(p)); got != name {
+			t.Errorf("expected %s got %s", name, got)
+		}
+	}
+}
+
+func TestVected_Flush(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(p)
+	for i := 1; i <= 3; i++ {
+		cmp.core().SetState(State{"count": i})
+		v.Flush()
+		if got := p.children[0].nodeValue; got != fmt.Sprint(i) {
+			t.Errorf("expected %d got %q", i, got)
+		}
+	}
+}
+
+func TestCore_SetStateCallbacks(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(p).core()
+	var seen []string
+	cmp.SetStateMode(No, State{"count": 1}, func() {
+		seen = append(seen, "first:"+p.children[0].nodeValue)
+	})
+	cmp.SetStateMode(No, State{"count": 2}, func() {
+		seen = append(seen, "second:"+p.children[0].nodeValue)
+	})
+	if n := cmp.pendingCallbacks(); n != 2 {
+		t.Errorf("expected 2 pending callbacks got %d", n)
+	}
+	cmp.SetState(State{"count": 3})
+	v.Flush()
+	if got := strings.Join(seen, ","); got != "first:3,second:3" {
+		t.Errorf("expected callbacks to run in order after the dom update got %s", got)
+	}
+	if n := cmp.pendingCallbacks(); n != 0 {
+		t.Errorf("expected no pending callbacks got %d", n)
+	}
+}
+
+func TestVected_selectValue(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	h := NewNode
+	option := func(value string) *Node {
+		return h(ElementNode, "", "option", Attrs(Attr("", "value", value)),
+			h(TextNode, "", strings.ToUpper(value), nil),
+		)
+	}
+	node := func(value string) *Node {
+		return h(ElementNode, "", "select", Attrs(Attr("", "value", value)),
+			option("a"),
+			h(ElementNode, "", "optgroup", nil, option("b")),
+			h(ElementNode, "", "option", nil, h(TextNode, "", "c", nil)),
+		)
+	}
+	selected := func(sel *object) (names []string) {
+		for _, o := range []*object{sel.children[0], sel.children[1].children[0], sel.children[2]} {
+			if s := o.Get("selected"); Valid(s) && s.Bool() {
+				names = append(names, optionValue(o))
+			}
+		}
+		return
+	}
+	el := newObject()
+	sel := v.Render(node("b"), el).(*object)
+	if got := fmt.Sprint(selected(sel)); got != "[b]" {
+		t.Errorf("expected [b] to be selected got %s", got)
+	}
+	v.Render(node("c"), el, sel)
+	if got := fmt.Sprint(selected(sel)); got != "[c]" {
+		t.Errorf("expected [c] to be selected got %s", got)
+	}
+	if got := sel.Get("value").String(); got != "c" {
+		t.Errorf("expected select value c got %s", got)
+	}
+}
+
+func TestCore_ClassFromState(t *testing.T) {
+	classes := map[string]string{
+		"is-active": "active",
+		"is-open":   "open",
+	}
+	var c Core
+	c.SetStateMode(No, State{"active": false, "open": "yes"})
+	if got := c.ClassFromState(classes); got != "" {
+		t.Errorf("expected no class got %q", got)
+	}
+	c.SetStateMode(No, State{"active": true})
+	if got := c.ClassFromState(classes); got != "is-active" {
+		t.Errorf("expected is-active got %q", got)
+	}
+	c.SetStateMode(No, State{"open": true})
+	if got := c.ClassFromState(classes); got != "is-active is-open" {
+		t.Errorf("expected is-active is-open got %q", got)
+	}
+}
+
+func TestNew_WithBackend(t *testing.T) {
+	var callbacks int
+	v := New(WithBackend(Backend{
+		Document: newObject(),
+		Callbacks: func(fn func([]Value)) Resource {
+			callbacks++
+			return release{}
+		},
+	}))
+	v.Register("counter", &counter{})
+	div := v.Render(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "button", Attrs(Attr("", "onClick", func([]Value) {}))),
+		NewNode(ElementNode, "", "counter", nil),
+	), newObject()).(*object)
+	var buf bytes.Buffer
+	renderObject(&buf, div)
+	expect := "<div><button></button><p>&lt;nil&gt;</p></div>"
+	if buf.String() != expect {
+		t.Errorf("expected %s got %s", expect, buf.String())
+	}
+	if callbacks == 0 {
+		t.Error("expected the backend to create the click listener")
+	}
+	if v.Backend().Document != v.Document {
+		t.Error("expected Backend to return the document")
+	}
+}
+
+// attachedSpy records whether its base is in the document when it mounts.
+type attachedSpy struct {
+	Core
+	mounted, attached bool
+}
+
+func (a *attachedSpy) ComponentDidMount() {
+	a.mounted = true
+	parent := a.base.Get("parentNode")
+	a.attached = Valid(parent) && Valid(parent.Get("parentNode"))
+}
+
+func (a *attachedSpy) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil)
+}
+
+func TestVected_RenderOffscreen(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	v.Register("attached", &attachedSpy{})
+	v.Register("spy", &unmountSpy{})
+	target := newObject()
+	previous := v.Render(NewNode(ElementNode, "", "spy", nil), target)
+	spy := v.findComponent(previous).(*unmountSpy)
+	elem, commit := v.RenderOffscreen(NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "counter", nil),
+		NewNode(ElementNode, "", "attached", nil),
+	))
+	attached := v.findComponent(elem.(*object).children[1]).(*attachedSpy)
+	if attached.mounted {
+		t.Error("expected components to be mounted on commit")
+	}
+	if len(target.children) != 1 || !IsEqual(target.children[0], previous) {
+		t.Fatal("expected target to be left alone before commit")
+	}
+	if p := elem.Get("parentNode"); Valid(p) {
+		t.Fatal("expected the rendered tree to be detached")
+	}
+	commit(target)
+	if len(target.children) != 1 || !IsEqual(target.children[0], elem) {
+		t.Fatal("expected commit to replace the previous tree")
+	}
+	if !spy.unmounted {
+		t.Error("expected the previous tree to be unmounted")
+	}
+	if !attached.mounted || !attached.attached {
+		t.Error("expected components to be mounted once in target")
+	}
+	if !v.Unmount(target) {
+		t.Error("expected the committed tree to be a root")
+	}
+}
+
+func TestVected_Pause(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(p)
+	v.Pause()
+	cmp.core().SetState(State{"count": 1})
+	cmp.core().SetState(State{"count": 2})
+	v.Flush()
+	if got := p.children[0].nodeValue; got != "<nil>" {
+		t.Fatalf("expected nothing to render while paused got %q", got)
+	}
+	if n := v.queue.Len(); n != 1 {
+		t.Errorf("expected 1 queued component got %d", n)
+	}
+	v.Resume()
+	if got := p.children[0].nodeValue; got != "2" {
+		t.Errorf("expected 2 after resume got %q", got)
+	}
+}
+
+func TestVected_Stats(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	el := newObject()
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), el).(*object)
+	if s := v.Stats(); s.Created != 2 || s.Updated != 0 {
+		t.Errorf("expected 2 created got %+v", s)
+	}
+	cmp := v.findComponent(p)
+	cmp.core().SetStateMode(No, State{"count": 1})
+	cmp.core().dirty = true
+	v.queue.Push(cmp)
+	s := v.Flush()
+	if s != (DiffStats{Updated: 1}) {
+		t.Errorf("expected 1 updated got %+v", s)
+	}
+
+	h := NewNode
+	list := func(keys ...string) *Node {
+		var children []*Node
+		for _, k := range keys {
+			children = append(children, h(ElementNode, "", "li", Attrs(Attr("", "key", k))))
+		}
+		return h(ElementNode, "", "ul", nil, children...)
+	}
+	ul := v.Render(list("a", "b", "c"), el)
+	v.Render(list("c", "a"), el, ul)
+	if s := v.Stats(); s.Moved != 1 || s.Removed != 1 || s.Created != 0 {
+		t.Errorf("expected 1 moved and 1 removed got %+v", s)
+	}
+
+	div := func(class string) *Node {
+		return h(ElementNode, "", "div", Attrs(Attr("", "className", class)),
+			h(ElementNode, "", "span", Attrs(Attr("", "id", "a"))),
+		)
+	}
+	root := v.Render(div("a"), el)
+	v.Render(div("b"), el, root)
+	if s := v.Stats(); s != (DiffStats{Updated: 1}) {
+		t.Errorf("expected the element whose class changed to be updated got %+v", s)
+	}
+	v.Render(div("b"), el, root)
+	if s := v.Stats(); s != (DiffStats{}) {
+		t.Errorf("expected no changes got %+v", s)
+	}
+}
+
+func TestVected_contentEditable(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	node := func(text string) *Node {
+		return NewNode(ElementNode, "", "div", Attrs(Attr("", "contenteditable", "true")),
+			NewNode(TextNode, "", text, nil),
+		)
+	}
+	el := newObject()
+	div := v.Render(node("hello"), el).(*object)
+
+	// typing usually leaves the text split in several nodes.
+	div.children[0].Set("nodeValue", "hel")
+	div.Call("appendChild", v.Document.Call("createTextNode", "lo"))
+	v.Render(node("hello"), el, div)
+	if len(div.children) != 2 || div.children[0].nodeValue != "hel" {
+		t.Errorf("expected unchanged content to be left alone got %v", div.children)
+	}
+	v.Render(node("bye"), el, div)
+	if got := div.Get("textContent").String(); got != "bye" {
+		t.Errorf("expected bye got %q", got)
+	}
+}
+
+func TestCore_SetStateFunc(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(p)
+	inc := func(s State) State {
+		n, _ := s["count"].(int)
+		return State{"count": n + 1}
+	}
+	var called int
+	v.Batch(func() {
+		cmp.core().SetStateFunc(inc, func() { called++ })
+		cmp.core().SetStateFunc(inc)
+		if cmp.core().state["count"] != nil {
+			t.Error("expected updaters to be applied when rendering")
+		}
+	})
+	if got := p.children[0].nodeValue; got != "2" {
+		t.Errorf("expected 2 got %q", got)
+	}
+	if called != 1 {
+		t.Errorf("expected the callback to be called once got %d", called)
+	}
+}
+
+type ctxKey struct{}
+
+type themed struct {
+	Core
+}
+
+func (th *themed) Render(ctx context.Context, props Props, state State) *Node {
+	theme, _ := ctx.Value(ctxKey{}).(string)
+	return NewNode(ElementNode, "", "p", nil, NewNode(TextNode, "", theme, nil))
+}
+
+func TestVected_RenderContext(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("themed", &themed{})
+	v.Register("spy", &unmountSpy{})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "dark")
+	el := newObject()
+	div := v.RenderContext(ctx, NewNode(ElementNode, "", "div", nil,
+		NewNode(ElementNode, "", "themed", nil),
+		NewNode(ElementNode, "", "spy", nil),
+	), el).(*object)
+	if got := div.children[0].children[0].nodeValue; got != "dark" {
+		t.Errorf("expected components to receive ctx got %q", got)
+	}
+	spy := v.findComponent(div.children[1]).(*unmountSpy)
+	if !v.Unmount(el) || !spy.unmounted {
+		t.Error("expected the tree to be unmounted")
+	}
+}
+
+func TestQueuedRender_coalesce(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &renderCounter{})
+	span := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(span).(*renderCounter)
+	v.Pause()
+	for i := 1; i <= 5; i++ {
+		cmp.SetState(State{"count": i})
+	}
+	if n := v.queue.Len(); n != 1 {
+		t.Errorf("expected the component to be queued once got %d", n)
+	}
+	v.Resume()
+	if cmp.renders != 2 {
+		t.Errorf("expected a single re render got %d", cmp.renders-1)
+	}
+	if got := span.children[0].nodeValue; got != "5" {
+		t.Errorf("expected 5 got %q", got)
+	}
+}
+
+type ordered struct {
+	Core
+	order *[]string
+}
+
+func (o *ordered) Render(ctx context.Context, props Props, state State) *Node {
+	if o.order != nil {
+		*o.order = append(*o.order, props.String("name"))
+	}
+	return NewNode(ElementNode, "", "i", nil)
+}
+
+func TestQueuedRender_schedule(t *testing.T) {
+	var frames []func()
+	v := New(WithBackend(Backend{
+		Document: newObject(),
+		Schedule: func(fn func()) {
+			frames = append(frames, fn)
+		},
+	}))
+	v.Register("ordered", &ordered{})
+	h := NewNode
+	div := v.Render(h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "ordered", Attrs(Attr("", "name", "low"))),
+		h(ElementNode, "", "ordered", Attrs(Attr("", "name", "high"))),
+		h(ElementNode, "", "ordered", Attrs(Attr("", "name", "mid"))),
+	), newObject()).(*object)
+	var order []string
+	priority := map[string]int{"low": 0, "high": 2, "mid": 1}
+	for _, ch := range div.children {
+		cmp := v.findComponent(ch).(*ordered)
+		cmp.order = &order
+		cmp.SetPriority(priority[cmp.props.String("name")])
+		cmp.SetState(State{"n": 1})
+	}
+	if len(frames) != 1 {
+		t.Fatalf("expected a single scheduled render got %d", len(frames))
+	}
+	frames[0]()
+	if got := fmt.Sprint(order); got != "[high mid low]" {
+		t.Errorf("expected [high mid low] got %s", got)
+	}
+	order = nil
+	v.findComponent(div.children[0]).core().SetState(State{"n": 2})
+	if len(frames) != 2 {
+		t.Fatalf("expected a new render to be scheduled after the drain got %d", len(frames))
+	}
+}
+
+func TestQueuedRender_priority(t *testing.T) {
+	q := newQueuedRender(New())
+	for i, p := range []int{0, 1, 0, 1, 2} {
+		c := &counter{}
+		c.id = i + 1
+		c.SetPriority(p)
+		q.Push(c)
+	}
+	var ids []int
+	for c := q.Pop(); c != nil; c = q.Pop() {
+		ids = append(ids, c.core().id)
+	}
+	// equal priorities keep the order they were queued in.
+	if got := fmt.Sprint(ids); got != "[5 2 4 1 3]" {
+		t.Errorf("expected [5 2 4 1 3] got %s", got)
+	}
+}
+
+func TestVected_Batch_concurrent(t *testing.T) {
+	// no backend scheduler, queued components are rendered from a timer.
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	p := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(p).(*counter)
+	rendered := make(chan struct{})
+	go cmp.SetState(State{"count": 1}, func() { close(rendered) })
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case <-rendered:
+			if got := p.children[0].nodeValue; got != "1" {
+				t.Errorf("expected 1 got %q", got)
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for the queued render")
+		default:
+			v.Batch(func() {})
+		}
+	}
+}
+
+// notifier dispatches an event on its element after every update.
+type notifier struct {
+	Core
+}
+
+func (n *notifier) ComponentDidUpdate(Props, State) {
+	n.base.(*object).dispatch("change", newObject())
+}
+
+func (n *notifier) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "input", Attrs(Attr("", "onChange", props["onChange"])))
+}
+
+func TestVected_Batch_duringRender(t *testing.T) {
+	v := New(WithBackend(Backend{
+		Document:  newObject(),
+		Callbacks: callbacks,
+		Schedule:  func(func()) {},
+	}))
+	v.Register("notifier", &notifier{})
+	v.Register("counter", &counter{})
+	var target *counter
+	change := func([]Value) {
+		// runs inside Batch while the queue is being drained.
+		target.SetState(State{"count": 1})
+	}
+	h := NewNode
+	div := v.Render(h(ElementNode, "", "div", nil,
+		h(ElementNode, "", "notifier", Attrs(Attr("", "onChange", change))),
+		h(ElementNode, "", "counter", nil),
+	), newObject()).(*object)
+	target = v.findComponent(div.children[1]).(*counter)
+	v.findComponent(div.children[0]).core().SetState(State{"n": 1})
+	done := make(chan struct{})
+	go func() {
+		v.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("flushing deadlocked on a handler fired during the drain")
+	}
+	if got := div.children[1].children[0].nodeValue; got != "1" {
+		t.Errorf("expected the drain to render the component updated by the handler got %q", got)
+	}
+}
+
+type gate struct {
+	Core
+	renders int
+}
+
+func (g *gate) ShouldComponentUpdate(ctx context.Context, props Props, state State) bool {
+	return props["update"] == true
+}
+
+func (g *gate) Render(ctx context.Context, props Props, state State) *Node {
+	g.renders++
+	return NewNode(ElementNode, "", "p", nil,
+		NewNode(TextNode, "", fmt.Sprint(props["count"]), nil),
+	)
+}
+
+func TestShouldUpdate(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("gate", &gate{})
+	node := func(update bool, count int) *Node {
+		return NewNode(ElementNode, "", "div", nil,
+			NewNode(ElementNode, "", "gate", Attrs(
+				Attr("", "update", update),
+				Attr("", "count", count),
+			)),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(false, 0), el).(*object)
+	p := div.children[0]
+	g := v.findComponent(p).(*gate)
+	v.Render(node(false, 1), el, div)
+	if g.renders != 1 || p.children[0].nodeValue != "0" {
+		t.Errorf("expected the update to be skipped got %d renders", g.renders)
+	}
+	if g.Props()["count"] != 1 {
+		t.Errorf("expected skipped props to be stored got %v", g.Props()["count"])
+	}
+	g.ForceUpdate()
+	if g.renders != 2 || p.children[0].nodeValue != "1" {
+		t.Errorf("expected ForceUpdate to bypass ShouldComponentUpdate got %d renders", g.renders)
+	}
+	v.Render(node(true, 2), el, div)
+	if g.renders != 3 || p.children[0].nodeValue != "2" {
+		t.Errorf("expected the update to render got %d renders", g.renders)
+	}
+}
+
+func TestCore_Disable(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &renderCounter{})
+	span := v.Render(NewNode(ElementNode, "", "counter", nil), newObject()).(*object)
+	cmp := v.findComponent(span).(*renderCounter)
+	cmp.Disable()
+	cmp.SetStateMode(Sync, State{"count": 1})
+	cmp.SetState(State{"count": 2})
+	v.Flush()
+	if cmp.renders != 1 || span.children[0].nodeValue != "<nil>" {
+		t.Errorf("expected no render while disabled got %d renders", cmp.renders)
+	}
+	cmp.Enable()
+	if cmp.renders != 2 || span.children[0].nodeValue != "2" {
+		t.Errorf("expected a render with the latest state got %d renders", cmp.renders)
+	}
+	cmp.SetStateMode(Sync, State{"count": 3})
+	if span.children[0].nodeValue != "3" {
+		t.Errorf("expected updates to resume got %s", span.children[0].nodeValue)
+	}
+}
+
+func TestSameComponent(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("gate", &gate{})
+	node := func(count int) *Node {
+		return NewNode(ElementNode, "", "div", nil,
+			NewNode(ElementNode, "", "gate", Attrs(
+				Attr("", "update", true),
+				Attr("", "count", count),
+			)),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(0), el).(*object)
+	before := v.findComponent(div.children[0])
+	v.Render(node(1), el, div)
+	after := v.findComponent(div.children[0])
+	if !SameComponent(before, after) {
+		t.Errorf("expected instance %d to be reused got %d", before.core().ID(), after.core().ID())
+	}
+	if SameComponent(before, &gate{}) || !SameComponent(nil, nil) {
+		t.Error("expected instances with different ids to differ")
+	}
+}
+
+type derived struct {
+	Core
+	seen []interface{}
+}
+
+func (d *derived) DeriveState(props Props, state State) State {
+	n, _ := props["count"].(int)
+	return State{"double": n * 2}
+}
+
+func (d *derived) ShouldComponentUpdate(ctx context.Context, props Props, state State) bool {
+	d.seen = append(d.seen, state["double"])
+	return true
+}
+
+func (d *derived) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil,
+		NewNode(TextNode, "", fmt.Sprint(state["double"]), nil),
+	)
+}
+
+func TestDerivedState(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("derived", &derived{})
+	node := func(count int) *Node {
+		return NewNode(ElementNode, "", "div", nil,
+			NewNode(ElementNode, "", "derived", Attrs(Attr("", "count", count))),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(1), el).(*object)
+	p := div.children[0]
+	if got := p.children[0].nodeValue; got != "2" {
+		t.Errorf("expected state derived on mount got %s", got)
+	}
+	v.Render(node(2), el, div)
+	if got := p.children[0].nodeValue; got != "4" {
+		t.Errorf("expected state derived on update got %s", got)
+	}
+	d := v.findComponent(p).(*derived)
+	if got := fmt.Sprint(d.seen); got != "[4]" {
+		t.Errorf("expected ShouldComponentUpdate to see the derived state got %s", got)
+	}
+}
+
+type receiver struct {
+	Core
+	received [][2]interface{}
+}
+
+func (r *receiver) ComponentWillReceiveProps(ctx context.Context, props Props) {
+	r.received = append(r.received, [2]interface{}{r.Props()["count"], props["count"]})
+}
+
+func (r *receiver) Render(ctx context.Context, props Props, state State) *Node {
+	return NewNode(ElementNode, "", "p", nil)
+}
+
+func TestWillReceiveProps(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("receiver", &receiver{})
+	node := func(count int) *Node {
+		return NewNode(ElementNode, "", "div", nil,
+			NewNode(ElementNode, "", "receiver", Attrs(Attr("", "count", count))),
+		)
+	}
+	el := newObject()
+	div := v.Render(node(1), el).(*object)
+	r := v.findComponent(div.children[0]).(*receiver)
+	if len(r.received) != 0 {
+		t.Fatalf("expected no call on mount got %v", r.received)
+	}
+	v.Render(node(2), el, div)
+	if got := fmt.Sprint(r.received); got != "[[1 2]]" {
+		t.Errorf("expected [[1 2]] got %s", got)
+	}
+}
+
+func TestRef(t *testing.T) {
+	v := New()
+	v.Document = newObject()
+	v.Register("counter", &counter{})
+	var elem, cmp Ref
+	node := func(show bool) *Node {
+		var children []*Node
+		if show {
+			children = append(children,
+				NewNode(ElementNode, "", "input", Attrs(Attr("", "ref", elem.Set))),
+				NewNode(ElementNode, "", "counter", Attrs(Attr("", "ref", cmp.Set))),
+			)
+		}
+		return NewNode(ElementNode, "", "div", nil, children...)
+	}
+	el := newObject()
+	div := v.Render(node(true), el).(*object)
+	if e, ok := elem.Current.(Element); !ok || !IsEqual(e, div.children[0]) {
+		t.Errorf("expected the input element got %v", elem.Current)
+	}
+	if c, ok := cmp.Current.(*counter); !ok || !SameComponent(c, v.findComponent(div.children[1])) {
+		t.Errorf("expected the counter component got %v", cmp.Current)
+	}
+	v.Render(node(false), el, div)
+	if elem.Current != nil || cmp.Current != nil {
+		t.Errorf("expected refs to be cleared on unmount got %v and %v", elem.Current, cmp.Current)
+	}
+}
+
+func TestOnScrollThrottled(t *testing.T) {
+	var frames []func()
+	var handlers []func([]Value)
+	v := New(WithBackend(Backend{
+		Document: newObject(),
+		Callbacks: func(fn func([]Value)) Resource {
+			handlers = append(handlers, fn)
+			return release{}
+		},
+		Schedule: func(fn func()) {
+			frames = append(frames, fn)
+		},
+	}))
+	var calls int
+	var last Value
+	scroll := func(args []Value) {
+		calls++
+		last = args[0]
+	}
+	h := NewNode
+	el := newObject()
+	tree := func(children ...*Node) *Node {
+		return h(ElementNode, "", "div", nil, children...)
+	}
+	v.Render(tree(h(ElementNode, "", "ul", Attrs(OnScrollThrottled(scroll)))), el)
+	fire := func(n int) Value {
+		var ev Value
+		for i := 0; i < n; i++ {
+			ev = newObject()
+			handlers[0]([]Value{ev})
+		}
+		return ev
+	}
+	ev := fire(100)
+	if len(frames) != 1 {
+		t.Fatalf("expected a single scheduled call got %d", len(frames))
+	}
+	frames[0]()
+	if calls != 1 {
+		t.Errorf("expected the handler to run once got %d", calls)
+	}
+	if last != ev {
+		t.Error("expected the handler to receive the last event")
+	}
+
+	// re rendering keeps the throttle of the first render.
+	v.Render(tree(h(ElementNode, "", "ul", Attrs(OnScrollThrottled(scroll)))), el, el.children[0])
+	fire(10)
+	if len(frames) != 2 {
+		t.Fatalf("expected a new call to be scheduled got %d", len(frames))
+	}
+	frames[1]()
+	if calls != 2 {
+		t.Errorf("expected the handler to run twice got %d", calls)
+	}
+
+	fire(10)
+	v.Render(tree(), el, el.children[0])
+	frames[2]()
+	if calls != 2 {
+		t.Errorf("expected pending calls to be dropped on unmount got %d calls", calls)
+	}
 }